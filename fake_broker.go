@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/logger"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/signal"
+	"github.com/vrischmann/envconfig"
+)
+
+const fakeBrokerCmd = "fake-broker"
+
+// FakeBrokerCmdConfig holds configuration of the fake broker started as a separate process,
+// e.g. as a Deployment in the test namespace.
+type FakeBrokerCmdConfig struct {
+	Logger logger.Config
+	Port   int `envconfig:"default=8080"`
+	Broker fakebroker.Config
+}
+
+// runFakeBroker serves the fake Open Service Broker API until the process is stopped
+func runFakeBroker() int {
+	var cfg FakeBrokerCmdConfig
+	err := envconfig.InitWithPrefix(&cfg, "APP")
+	fatalOnError(err, "while reading configuration from environment variables")
+
+	log := logger.New(&cfg.Logger)
+	stopCh := signal.SetupChannel()
+
+	log.Infof("Starting fake broker on port %d", cfg.Port)
+	runHTTPServer(stopCh, fmt.Sprintf(":%d", cfg.Port), log, rootRoutes{fakebroker.New(cfg.Broker, log)})
+	return 0
+}
+
+// rootRoutes registers given handler as the default route
+type rootRoutes struct {
+	http.Handler
+}
+
+// RegisterRoutes registers the handler in given mux
+func (r rootRoutes) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("/", r.Handler)
+}
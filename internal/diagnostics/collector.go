@@ -0,0 +1,257 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const logsLimitBytes = 5 * 1048576 // 5MB
+
+// Config holds configuration for the diagnostic bundles collector
+type Config struct {
+	Enabled bool `envconfig:"default=true"`
+	// Dir is the directory where bundles are stored. Each bundle is stored in the subdirectory named by the run ID.
+	Dir string `envconfig:"default=/tmp/diagnostics"`
+	// MaxBundles defines how many bundles are kept. The oldest bundles are removed first.
+	MaxBundles int `envconfig:"default=50"`
+	// ExternalURL is the address under which the tester HTTP server is available for the notification readers,
+	// e.g. `https://stressor.my-cluster.example.com`. If not set, the notification contains only the bundle location.
+	ExternalURL                 string `envconfig:"optional"`
+	ControllerManagerNamespace  string `envconfig:"default=kyma-system"`
+	ControllerManagerDeployment string `envconfig:"default=core-catalog-controller-manager"`
+}
+
+// Collector collects the diagnostic bundle for the failed test:
+// - ServiceInstances and ServiceBindings from the test namespace,
+// - Events from the test namespace,
+// - ClusterServiceBrokers and classes and plans used by the ServiceInstances,
+// - Service Catalog controller-manager logs from the test run window.
+type Collector struct {
+	cfg    Config
+	k8sCli kubernetes.Interface
+	scCli  scClient.Interface
+	log    logrus.FieldLogger
+}
+
+// NewCollector returns new instance of the Collector
+func NewCollector(cfg Config, k8sCli kubernetes.Interface, scCli scClient.Interface, log logrus.FieldLogger) *Collector {
+	return &Collector{
+		cfg:    cfg,
+		k8sCli: k8sCli,
+		scCli:  scCli,
+		log:    log.WithField("service", "diagnostics:collector"),
+	}
+}
+
+// Enabled returns true when the diagnostic bundles are collected
+func (c *Collector) Enabled() bool {
+	return c.cfg.Enabled
+}
+
+// Collect stores the diagnostic bundle for the test run with given ID and returns its location.
+// The bundle is stored also when some of the resources cannot be collected, errors are saved in the `errors.txt` file.
+func (c *Collector) Collect(runID, namespace string, since time.Time) (string, error) {
+	if !c.cfg.Enabled {
+		return "", errors.New("diagnostics collecting is disabled")
+	}
+	if runID == "" {
+		runID = fmt.Sprintf("run-%d", time.Now().Unix())
+	}
+
+	dir := filepath.Join(c.cfg.Dir, runID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrapf(err, "while creating bundle directory %q", dir)
+	}
+
+	bundle := &bundleWriter{dir: dir}
+	c.collectNamespacedResources(bundle, namespace)
+	c.collectCatalogResources(bundle, namespace)
+	c.collectControllerManagerLogs(bundle, since)
+	bundle.writeErrors()
+
+	c.log.WithField("ID", runID).Infof("Diagnostic bundle stored in %s", dir)
+	c.prune()
+
+	return c.location(runID, dir), nil
+}
+
+func (c *Collector) collectNamespacedResources(bundle *bundleWriter, namespace string) {
+	sc := c.scCli.ServicecatalogV1beta1()
+
+	instances, err := sc.ServiceInstances(namespace).List(metav1.ListOptions{})
+	bundle.writeYAML("serviceinstances.yaml", instances, errors.Wrap(err, "while listing ServiceInstances"))
+
+	bindings, err := sc.ServiceBindings(namespace).List(metav1.ListOptions{})
+	bundle.writeYAML("servicebindings.yaml", bindings, errors.Wrap(err, "while listing ServiceBindings"))
+
+	brokers, err := sc.ServiceBrokers(namespace).List(metav1.ListOptions{})
+	bundle.writeYAML("servicebrokers.yaml", brokers, errors.Wrap(err, "while listing ServiceBrokers"))
+
+	events, err := c.k8sCli.CoreV1().Events(namespace).List(metav1.ListOptions{})
+	if events != nil {
+		sort.Slice(events.Items, func(i, j int) bool {
+			return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+		})
+	}
+	bundle.writeYAML("events.yaml", events, errors.Wrap(err, "while listing Events"))
+}
+
+// collectCatalogResources collects all ClusterServiceBrokers and classes and plans referenced by ServiceInstances
+func (c *Collector) collectCatalogResources(bundle *bundleWriter, namespace string) {
+	sc := c.scCli.ServicecatalogV1beta1()
+
+	brokers, err := sc.ClusterServiceBrokers().List(metav1.ListOptions{})
+	bundle.writeYAML("clusterservicebrokers.yaml", brokers, errors.Wrap(err, "while listing ClusterServiceBrokers"))
+
+	instances, err := sc.ServiceInstances(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return
+	}
+
+	var objs []interface{}
+	for _, si := range instances.Items {
+		if ref := si.Spec.ClusterServiceClassRef; ref != nil {
+			class, err := sc.ClusterServiceClasses().Get(ref.Name, metav1.GetOptions{})
+			objs = c.appendOrRecord(bundle, objs, class, errors.Wrapf(err, "while getting ClusterServiceClass %q", ref.Name))
+		}
+		if ref := si.Spec.ClusterServicePlanRef; ref != nil {
+			plan, err := sc.ClusterServicePlans().Get(ref.Name, metav1.GetOptions{})
+			objs = c.appendOrRecord(bundle, objs, plan, errors.Wrapf(err, "while getting ClusterServicePlan %q", ref.Name))
+		}
+		if ref := si.Spec.ServiceClassRef; ref != nil {
+			class, err := sc.ServiceClasses(namespace).Get(ref.Name, metav1.GetOptions{})
+			objs = c.appendOrRecord(bundle, objs, class, errors.Wrapf(err, "while getting ServiceClass %q", ref.Name))
+		}
+		if ref := si.Spec.ServicePlanRef; ref != nil {
+			plan, err := sc.ServicePlans(namespace).Get(ref.Name, metav1.GetOptions{})
+			objs = c.appendOrRecord(bundle, objs, plan, errors.Wrapf(err, "while getting ServicePlan %q", ref.Name))
+		}
+	}
+	bundle.writeYAML("classes-and-plans.yaml", objs, nil)
+}
+
+func (c *Collector) appendOrRecord(bundle *bundleWriter, objs []interface{}, obj interface{}, err error) []interface{} {
+	if err != nil {
+		bundle.recordErr(err)
+		return objs
+	}
+	return append(objs, obj)
+}
+
+func (c *Collector) collectControllerManagerLogs(bundle *bundleWriter, since time.Time) {
+	ns, name := c.cfg.ControllerManagerNamespace, c.cfg.ControllerManagerDeployment
+	deploy, err := c.k8sCli.AppsV1().Deployments(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		bundle.recordErr(errors.Wrapf(err, "while getting Deployment %s/%s", ns, name))
+		return
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		bundle.recordErr(errors.Wrapf(err, "while converting selector of Deployment %s/%s", ns, name))
+		return
+	}
+
+	pods, err := c.k8sCli.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		bundle.recordErr(errors.Wrapf(err, "while listing Pods of Deployment %s/%s", ns, name))
+		return
+	}
+
+	sinceTime := metav1.NewTime(since)
+	limit := int64(logsLimitBytes)
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			req := c.k8sCli.CoreV1().Pods(ns).GetLogs(pod.Name, &k8sCoreTypes.PodLogOptions{
+				Container:  container.Name,
+				SinceTime:  &sinceTime,
+				LimitBytes: &limit,
+				Timestamps: true,
+			})
+			logs, err := req.DoRaw()
+			bundle.write(fmt.Sprintf("logs-%s-%s.log", pod.Name, container.Name), logs,
+				errors.Wrapf(err, "while getting logs of container %s in Pod %s/%s", container.Name, ns, pod.Name))
+		}
+	}
+}
+
+func (c *Collector) location(runID, dir string) string {
+	if c.cfg.ExternalURL == "" {
+		return fmt.Sprintf("%s (available under the %s%s/ endpoint)", dir, routePrefix, runID)
+	}
+	return fmt.Sprintf("%s%s%s/", strings.TrimSuffix(c.cfg.ExternalURL, "/"), routePrefix, runID)
+}
+
+// prune removes the oldest bundles when there are more than configured
+func (c *Collector) prune() {
+	entries, err := ioutil.ReadDir(c.cfg.Dir)
+	if err != nil {
+		c.log.Errorf("Cannot read diagnostics directory: %v", err)
+		return
+	}
+	if len(entries) <= c.cfg.MaxBundles {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime().Before(entries[j].ModTime()) })
+	for _, e := range entries[:len(entries)-c.cfg.MaxBundles] {
+		if err := os.RemoveAll(filepath.Join(c.cfg.Dir, e.Name())); err != nil {
+			c.log.Errorf("Cannot remove old diagnostic bundle %s: %v", e.Name(), err)
+		}
+	}
+}
+
+// bundleWriter writes files into the bundle directory and collects errors
+type bundleWriter struct {
+	dir  string
+	errs []string
+}
+
+func (b *bundleWriter) writeYAML(name string, obj interface{}, collectErr error) {
+	if collectErr != nil {
+		b.recordErr(collectErr)
+		return
+	}
+
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		b.recordErr(errors.Wrapf(err, "while marshalling %s", name))
+		return
+	}
+	b.write(name, out, nil)
+}
+
+func (b *bundleWriter) write(name string, content []byte, collectErr error) {
+	if collectErr != nil {
+		b.recordErr(collectErr)
+		return
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(b.dir, name), content, 0644); err != nil {
+		b.recordErr(errors.Wrapf(err, "while writing %s", name))
+	}
+}
+
+func (b *bundleWriter) recordErr(err error) {
+	b.errs = append(b.errs, err.Error())
+}
+
+func (b *bundleWriter) writeErrors() {
+	if len(b.errs) == 0 {
+		return
+	}
+	b.write("errors.txt", []byte(strings.Join(b.errs, "\n")), nil)
+}
@@ -0,0 +1,25 @@
+package diagnostics
+
+import (
+	"net/http"
+)
+
+const routePrefix = "/diagnostics/"
+
+// Handler exposes stored diagnostic bundles over HTTP:
+// - GET /diagnostics/                  - lists stored bundles
+// - GET /diagnostics/{runID}/          - lists files of the bundle
+// - GET /diagnostics/{runID}/{file}    - returns given file of the bundle
+type Handler struct {
+	dir string
+}
+
+// NewHandler returns new instance of Handler
+func NewHandler(cfg Config) *Handler {
+	return &Handler{dir: cfg.Dir}
+}
+
+// RegisterRoutes registers diagnostics endpoints in given mux
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle(routePrefix, http.StripPrefix(routePrefix, http.FileServer(http.Dir(h.dir))))
+}
@@ -0,0 +1,236 @@
+// Package etcd provides a minimal etcd v3 client used by the etcd stress test.
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// compactedErrMsg is returned by etcd when the requested revision was removed by the compaction
+const compactedErrMsg = "required revision has been compacted"
+
+// TLSConfig holds PEM encoded client certificate, key and CA used to connect to etcd
+type TLSConfig struct {
+	Cert               []byte
+	Key                []byte
+	CA                 []byte
+	InsecureSkipVerify bool
+}
+
+// Client is a minimal etcd v3 client which uses the JSON gRPC gateway, so no gRPC dependencies are required
+type Client struct {
+	endpoint  string
+	apiPrefix string
+	httpCli   *http.Client
+}
+
+// KeyValue is a single key stored in etcd
+type KeyValue struct {
+	Key         string
+	Value       []byte
+	ModRevision int64
+}
+
+// Status describes the etcd member
+type Status struct {
+	Version  string
+	DBSize   int64
+	Revision int64
+}
+
+// NewClient returns new instance of the Client. The apiPrefix depends on the etcd version,
+// e.g. `/v3beta` for etcd 3.3 and `/v3` for etcd 3.4.
+func NewClient(endpoint, apiPrefix string, tlsCfg *TLSConfig, timeout time.Duration) (*Client, error) {
+	transport := &http.Transport{}
+	if tlsCfg != nil {
+		cert, err := tls.X509KeyPair(tlsCfg.Cert, tlsCfg.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "while loading client certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: tlsCfg.InsecureSkipVerify,
+		}
+		// without the CA the system roots are used
+		if len(tlsCfg.CA) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(tlsCfg.CA) {
+				return nil, errors.New("cannot parse CA certificate")
+			}
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+
+	return &Client{
+		endpoint:  strings.TrimSuffix(endpoint, "/"),
+		apiPrefix: apiPrefix,
+		httpCli:   &http.Client{Transport: transport, Timeout: timeout},
+	}, nil
+}
+
+// Put stores given value under the key and returns the revision of the store after the change
+func (c *Client) Put(ctx context.Context, key string, value []byte) (int64, error) {
+	var resp struct {
+		Header header `json:"header"`
+	}
+	err := c.call(ctx, "/kv/put", map[string]interface{}{
+		"key":   encode(key),
+		"value": base64.StdEncoding.EncodeToString(value),
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return int64(resp.Header.Revision), nil
+}
+
+// Get returns the key at given revision. Zero revision means the latest one.
+// Use IsCompacted to check if the revision was already compacted.
+func (c *Client) Get(ctx context.Context, key string, revision int64) (*KeyValue, error) {
+	req := map[string]interface{}{"key": encode(key)}
+	if revision > 0 {
+		req["revision"] = strconv.FormatInt(revision, 10)
+	}
+
+	var resp struct {
+		Kvs []struct {
+			Key         string    `json:"key"`
+			Value       string    `json:"value"`
+			ModRevision jsonInt64 `json:"mod_revision"`
+		} `json:"kvs"`
+	}
+	if err := c.call(ctx, "/kv/range", req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, errors.Errorf("key %q not found", key)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(resp.Kvs[0].Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "while decoding value")
+	}
+	return &KeyValue{Key: key, Value: value, ModRevision: int64(resp.Kvs[0].ModRevision)}, nil
+}
+
+// DeletePrefix removes all keys with given prefix and returns the number of removed keys
+func (c *Client) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	var resp struct {
+		Deleted jsonInt64 `json:"deleted"`
+	}
+	err := c.call(ctx, "/kv/deleterange", map[string]interface{}{
+		"key":       encode(prefix),
+		"range_end": encode(prefixEnd(prefix)),
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return int64(resp.Deleted), nil
+}
+
+// Status returns the status of the etcd member which handled the request
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var resp struct {
+		Header  header    `json:"header"`
+		Version string    `json:"version"`
+		DBSize  jsonInt64 `json:"dbSize"`
+	}
+	if err := c.call(ctx, "/maintenance/status", map[string]interface{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return &Status{Version: resp.Version, DBSize: int64(resp.DBSize), Revision: int64(resp.Header.Revision)}, nil
+}
+
+// IsCompacted returns true when the error was caused by reading compacted revision
+func IsCompacted(err error) bool {
+	return err != nil && strings.Contains(err.Error(), compactedErrMsg)
+}
+
+func (c *Client) call(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return errors.Wrap(err, "while encoding request")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+c.apiPrefix+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "while creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpCli.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "while calling %s", path)
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "while reading response of %s", path)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(raw, &e)
+		msg := e.Error
+		if msg == "" {
+			msg = e.Message
+		}
+		if msg == "" {
+			msg = strings.TrimSpace(string(raw))
+		}
+		return fmt.Errorf("%s failed with status code %d: %s", path, resp.StatusCode, msg)
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return errors.Wrapf(err, "while decoding response of %s", path)
+	}
+	return nil
+}
+
+type header struct {
+	Revision jsonInt64 `json:"revision"`
+}
+
+// jsonInt64 decodes int64 values which the gRPC gateway encodes as strings
+type jsonInt64 int64
+
+func (i *jsonInt64) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt64(v)
+	return nil
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// prefixEnd returns the range end which covers all keys with given prefix
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// all keys after the prefix
+	return "\x00"
+}
@@ -0,0 +1,333 @@
+package janitor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset/typed/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	bucClient "github.com/kyma-project/kyma/components/binding-usage-controller/pkg/client/clientset/versioned/typed/servicecatalog/v1alpha1"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// NamespaceJanitorConfig holds configuration for the NamespaceJanitor
+type NamespaceJanitorConfig struct {
+	Enabled bool `envconfig:"default=true"`
+	// Interval defines how often the leaked namespaces are searched
+	Interval time.Duration `envconfig:"default=10m"`
+	// Threshold defines the age after which the test namespace is treated as leaked.
+	// It should be greater than the maximum runtime of tests.
+	Threshold time.Duration `envconfig:"default=1h"`
+	// RemoveFinalizers defines if finalizers of ServiceBindings and ServiceInstances
+	// which are stuck in deletion should be removed
+	RemoveFinalizers bool `envconfig:"default=false"`
+}
+
+// SlackNotifier allows sending notification about messages to Slack channel.
+type SlackNotifier interface {
+	Notify(id, header, details string) error
+}
+
+// NamespaceJanitor periodically removes test namespaces and cluster-wide UsageKinds which were not deleted by tests,
+// e.g. because the tester was killed during the test execution, and reports them as leaks.
+type NamespaceJanitor struct {
+	cfg           NamespaceJanitorConfig
+	coreCli       corev1.CoreV1Interface
+	scCli         scClient.ServicecatalogV1beta1Interface
+	bucCli        bucClient.ServicecatalogV1alpha1Interface
+	slackNotifier SlackNotifier
+	log           logrus.FieldLogger
+
+	// reported holds namespaces which were already reported, so namespaces stuck in the Terminating phase
+	// are not reported on each sweep
+	reported map[string]struct{}
+	// reportedKinds holds UsageKinds which were already reported, so UsageKinds which cannot be deleted
+	// are not reported on each sweep
+	reportedKinds map[string]struct{}
+}
+
+// leak describes the leaked test namespace
+type leak struct {
+	namespace string
+	runID     string
+	age       time.Duration
+	phase     k8sCoreTypes.NamespacePhase
+	bindings  int
+	instances int
+	errs      []string
+}
+
+// kindLeak describes the leaked UsageKind
+type kindLeak struct {
+	name  string
+	runID string
+	age   time.Duration
+	err   string
+}
+
+// NewNamespaceJanitor returns new instance of the NamespaceJanitor
+func NewNamespaceJanitor(cfg NamespaceJanitorConfig, coreCli corev1.CoreV1Interface, scCli scClient.ServicecatalogV1beta1Interface, bucCli bucClient.ServicecatalogV1alpha1Interface, slackNotifier SlackNotifier, log logrus.FieldLogger) *NamespaceJanitor {
+	return &NamespaceJanitor{
+		cfg:           cfg,
+		coreCli:       coreCli,
+		scCli:         scCli,
+		bucCli:        bucCli,
+		slackNotifier: slackNotifier,
+		log:           log.WithField("service", "janitor:namespace"),
+		reported:      map[string]struct{}{},
+		reportedKinds: map[string]struct{}{},
+	}
+}
+
+// Run searches for leaked namespaces in a loop until stop channel is closed
+func (j *NamespaceJanitor) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.sweep(); err != nil {
+			j.log.Errorf("Got error while cleaning up leaked test namespaces: %v", err)
+		}
+
+		select {
+		case <-stopCh:
+			j.log.Debug("Stop channel called. Shutdown namespace janitor")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *NamespaceJanitor) sweep() error {
+	nsList, err := j.coreCli.Namespaces().List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=true", labels.TestNamespace),
+	})
+	if err != nil {
+		return errors.Wrap(err, "while listing test namespaces")
+	}
+
+	var leaks []leak
+	existing := map[string]struct{}{}
+	for _, ns := range nsList.Items {
+		existing[ns.Name] = struct{}{}
+
+		age := time.Since(j.creationTime(ns.ObjectMeta))
+		if age < j.cfg.Threshold {
+			continue
+		}
+
+		l := j.cleanUp(ns)
+		l.age = age
+		if _, found := j.reported[ns.Name]; found {
+			j.log.Debugf("Leaked namespace %s already reported", ns.Name)
+			continue
+		}
+		leaks = append(leaks, l)
+	}
+
+	// forget namespaces which are already removed, so the map does not grow
+	for name := range j.reported {
+		if _, found := existing[name]; !found {
+			delete(j.reported, name)
+		}
+	}
+
+	kindLeaks, kindsErr := j.sweepUsageKinds()
+	if len(leaks) == 0 && len(kindLeaks) == 0 {
+		return kindsErr
+	}
+	if err := j.notify(leaks, kindLeaks); err != nil {
+		return err
+	}
+	return kindsErr
+}
+
+// sweepUsageKinds deletes UsageKinds created by tests which are older than the threshold
+// and returns those which were not reported yet
+func (j *NamespaceJanitor) sweepUsageKinds() ([]kindLeak, error) {
+	kindList, err := j.bucCli.UsageKinds().List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=true", labels.UsageKind),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "while listing test UsageKinds")
+	}
+
+	var leaks []kindLeak
+	existing := map[string]struct{}{}
+	for _, kind := range kindList.Items {
+		existing[kind.Name] = struct{}{}
+
+		age := time.Since(j.creationTime(kind.ObjectMeta))
+		if age < j.cfg.Threshold {
+			continue
+		}
+
+		l := kindLeak{name: kind.Name, runID: kind.Labels[labels.RunID], age: age}
+		j.log.WithField("ID", l.runID).Infof("Deleting leaked UsageKind %s", kind.Name)
+		if err := j.bucCli.UsageKinds().Delete(kind.Name, &metav1.DeleteOptions{}); err != nil && !apiErrors.IsNotFound(err) {
+			l.err = fmt.Sprintf("cannot delete UsageKind: %v", err)
+			j.log.WithField("ID", l.runID).Errorf("Got error while deleting leaked UsageKind %s: %s", kind.Name, l.err)
+		}
+
+		if _, found := j.reportedKinds[kind.Name]; found {
+			j.log.Debugf("Leaked UsageKind %s already reported", kind.Name)
+			continue
+		}
+		leaks = append(leaks, l)
+	}
+
+	for name := range j.reportedKinds {
+		if _, found := existing[name]; !found {
+			delete(j.reportedKinds, name)
+		}
+	}
+
+	return leaks, nil
+}
+
+// creationTime returns the time from the created-at label set by tests, or the creation timestamp when the label is missing
+func (j *NamespaceJanitor) creationTime(meta metav1.ObjectMeta) time.Time {
+	if raw, found := meta.Labels[labels.CreatedAt]; found {
+		if sec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+	return meta.CreationTimestamp.Time
+}
+
+// cleanUp deletes ServiceBindings, ServiceInstances and the namespace itself.
+// Errors are collected in the returned leak, so the clean-up is executed as far as possible.
+func (j *NamespaceJanitor) cleanUp(ns k8sCoreTypes.Namespace) leak {
+	l := leak{
+		namespace: ns.Name,
+		runID:     ns.Labels[labels.RunID],
+		phase:     ns.Status.Phase,
+	}
+	nsLogger := j.log.WithField("ID", l.runID)
+	nsLogger.Infof("Cleaning up leaked test namespace %s", ns.Name)
+
+	bindingCli := j.scCli.ServiceBindings(ns.Name)
+	bindings, err := bindingCli.List(metav1.ListOptions{})
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("cannot list ServiceBindings: %v", err))
+	} else {
+		l.bindings = len(bindings.Items)
+		for _, b := range bindings.Items {
+			if err := j.deleteBinding(bindingCli, b); err != nil {
+				l.errs = append(l.errs, err.Error())
+			}
+		}
+	}
+
+	instanceCli := j.scCli.ServiceInstances(ns.Name)
+	instances, err := instanceCli.List(metav1.ListOptions{})
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("cannot list ServiceInstances: %v", err))
+	} else {
+		l.instances = len(instances.Items)
+		for _, si := range instances.Items {
+			if err := j.deleteInstance(instanceCli, si); err != nil {
+				l.errs = append(l.errs, err.Error())
+			}
+		}
+	}
+
+	if ns.DeletionTimestamp == nil {
+		err := j.coreCli.Namespaces().Delete(ns.Name, &metav1.DeleteOptions{})
+		if err != nil && !apiErrors.IsNotFound(err) {
+			l.errs = append(l.errs, fmt.Sprintf("cannot delete namespace: %v", err))
+		}
+	}
+
+	for _, e := range l.errs {
+		nsLogger.Errorf("Got error while cleaning up leaked test namespace %s: %s", ns.Name, e)
+	}
+
+	return l
+}
+
+func (j *NamespaceJanitor) deleteBinding(cli scClient.ServiceBindingInterface, b scTypes.ServiceBinding) error {
+	if b.DeletionTimestamp == nil {
+		if err := cli.Delete(b.Name, &metav1.DeleteOptions{}); err != nil && !apiErrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete ServiceBinding %s", b.Name)
+		}
+		return nil
+	}
+
+	if !j.cfg.RemoveFinalizers || len(b.Finalizers) == 0 {
+		return nil
+	}
+
+	j.log.Infof("Removing finalizers %v from ServiceBinding %s/%s stuck in deletion", b.Finalizers, b.Namespace, b.Name)
+	b.Finalizers = nil
+	if _, err := cli.Update(&b); err != nil && !apiErrors.IsNotFound(err) {
+		return errors.Wrapf(err, "cannot remove finalizers from ServiceBinding %s", b.Name)
+	}
+	return nil
+}
+
+func (j *NamespaceJanitor) deleteInstance(cli scClient.ServiceInstanceInterface, si scTypes.ServiceInstance) error {
+	if si.DeletionTimestamp == nil {
+		if err := cli.Delete(si.Name, &metav1.DeleteOptions{}); err != nil && !apiErrors.IsNotFound(err) {
+			return errors.Wrapf(err, "cannot delete ServiceInstance %s", si.Name)
+		}
+		return nil
+	}
+
+	if !j.cfg.RemoveFinalizers || len(si.Finalizers) == 0 {
+		return nil
+	}
+
+	j.log.Infof("Removing finalizers %v from ServiceInstance %s/%s stuck in deletion", si.Finalizers, si.Namespace, si.Name)
+	si.Finalizers = nil
+	if _, err := cli.Update(&si); err != nil && !apiErrors.IsNotFound(err) {
+		return errors.Wrapf(err, "cannot remove finalizers from ServiceInstance %s", si.Name)
+	}
+	return nil
+}
+
+func (j *NamespaceJanitor) notify(leaks []leak, kindLeaks []kindLeak) error {
+	sort.Slice(leaks, func(i, k int) bool { return leaks[i].namespace < leaks[k].namespace })
+	sort.Slice(kindLeaks, func(i, k int) bool { return kindLeaks[i].name < kindLeaks[k].name })
+
+	details := &bytes.Buffer{}
+	for _, l := range leaks {
+		fmt.Fprintf(details, "• Namespace *%s* [run ID: %s, age: %v, phase: %s, ServiceBindings: %d, ServiceInstances: %d]\n",
+			l.namespace, l.runID, l.age.Round(time.Minute), l.phase, l.bindings, l.instances)
+		for _, e := range l.errs {
+			fmt.Fprintf(details, "    clean-up error: %s\n", e)
+		}
+	}
+	for _, l := range kindLeaks {
+		fmt.Fprintf(details, "• UsageKind *%s* [run ID: %s, age: %v]\n", l.name, l.runID, l.age.Round(time.Minute))
+		if l.err != "" {
+			fmt.Fprintf(details, "    clean-up error: %s\n", l.err)
+		}
+	}
+
+	id := uuid.NewV4().String()
+	j.log.WithField("ID", id).Infof("Found %d leaked test namespaces and %d leaked UsageKinds:\n%s", len(leaks), len(kindLeaks), details.String())
+
+	header := fmt.Sprintf("*[Phase: CLEANUP]* _Found %d leaked test namespaces and %d leaked UsageKinds_", len(leaks), len(kindLeaks))
+	if err := j.slackNotifier.Notify(id, header, details.String()); err != nil {
+		return errors.Wrap(err, "while sending Slack notification")
+	}
+
+	for _, l := range leaks {
+		j.reported[l.namespace] = struct{}{}
+	}
+	for _, l := range kindLeaks {
+		j.reportedKinds[l.name] = struct{}{}
+	}
+	return nil
+}
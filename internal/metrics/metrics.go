@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const route = "/metrics"
+
+// Escaping rules of the Prometheus text format. Label values escape the backslash, the double-quote and the line feed,
+// the help text escapes only the backslash and the line feed.
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// Registry holds metrics exposed in the Prometheus text format.
+// It implements only the histograms needed by the tester, so the Prometheus client library is not required.
+type Registry struct {
+	mu         sync.RWMutex
+	histograms []*HistogramVec
+}
+
+// NewRegistry returns new instance of Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewHistogramVec creates the histogram partitioned by given labels and registers it
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:       name,
+		help:       help,
+		buckets:    append([]float64(nil), buckets...),
+		labelNames: labelNames,
+		series:     map[string]*histogram{},
+	}
+	sort.Float64s(h.buckets)
+
+	r.mu.Lock()
+	r.histograms = append(r.histograms, h)
+	r.mu.Unlock()
+
+	return h
+}
+
+// RegisterRoutes registers the metrics endpoint in given mux
+func (r *Registry) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(route, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.write(w)
+	})
+}
+
+func (r *Registry) write(w io.Writer) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, h := range r.histograms {
+		h.write(w)
+	}
+}
+
+// HistogramVec is the histogram partitioned by labels
+type HistogramVec struct {
+	name       string
+	help       string
+	buckets    []float64
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	labels string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe adds the observation with given label values, which must be passed in the order of the label names
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	labels := h.formatLabels(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, found := h.series[labels]
+	if !found {
+		s = &histogram{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.series[labels] = s
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) formatLabels(values []string) string {
+	var pairs []string
+	for i, name := range h.labelNames {
+		var value string
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(value)))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", h.name, helpEscaper.Replace(h.help))
+	fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := h.series[k]
+		sep := ""
+		if s.labels != "" {
+			sep = ","
+		}
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s%sle=\"%g\"} %d\n", h.name, s.labels, sep, upper, s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", h.name, s.labels, sep, s.count)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", h.name, s.labels, s.sum)
+		fmt.Fprintf(w, "%s_count{%s} %d\n", h.name, s.labels, s.count)
+	}
+}
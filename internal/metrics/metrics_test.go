@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	phases := r.NewHistogramVec("tester_phase_seconds", "Duration of phases\nin seconds", []float64{5, 1}, "kind", "phase")
+	phases.Observe(0.5, "instance", "provisioning")
+	phases.Observe(3, "instance", "provisioning")
+	phases.Observe(10, "binding", `say "hi"`)
+	phases.Observe(1, `C:\tmp`, "line\nbreak")
+
+	r.NewHistogramVec("tester_empty_seconds", `Histogram without observations, with \ in help`, []float64{1})
+
+	unlabeled := r.NewHistogramVec("tester_unlabeled_seconds", "Histogram without labels", []float64{1})
+	unlabeled.Observe(2)
+
+	exp := `# HELP tester_phase_seconds Duration of phases\nin seconds
+# TYPE tester_phase_seconds histogram
+tester_phase_seconds_bucket{kind="C:\\tmp",phase="line\nbreak",le="1"} 1
+tester_phase_seconds_bucket{kind="C:\\tmp",phase="line\nbreak",le="5"} 1
+tester_phase_seconds_bucket{kind="C:\\tmp",phase="line\nbreak",le="+Inf"} 1
+tester_phase_seconds_sum{kind="C:\\tmp",phase="line\nbreak"} 1
+tester_phase_seconds_count{kind="C:\\tmp",phase="line\nbreak"} 1
+tester_phase_seconds_bucket{kind="binding",phase="say \"hi\"",le="1"} 0
+tester_phase_seconds_bucket{kind="binding",phase="say \"hi\"",le="5"} 0
+tester_phase_seconds_bucket{kind="binding",phase="say \"hi\"",le="+Inf"} 1
+tester_phase_seconds_sum{kind="binding",phase="say \"hi\""} 10
+tester_phase_seconds_count{kind="binding",phase="say \"hi\""} 1
+tester_phase_seconds_bucket{kind="instance",phase="provisioning",le="1"} 1
+tester_phase_seconds_bucket{kind="instance",phase="provisioning",le="5"} 2
+tester_phase_seconds_bucket{kind="instance",phase="provisioning",le="+Inf"} 2
+tester_phase_seconds_sum{kind="instance",phase="provisioning"} 3.5
+tester_phase_seconds_count{kind="instance",phase="provisioning"} 2
+# HELP tester_empty_seconds Histogram without observations, with \\ in help
+# TYPE tester_empty_seconds histogram
+# HELP tester_unlabeled_seconds Histogram without labels
+# TYPE tester_unlabeled_seconds histogram
+tester_unlabeled_seconds_bucket{le="1"} 0
+tester_unlabeled_seconds_bucket{le="+Inf"} 1
+tester_unlabeled_seconds_sum{} 2
+tester_unlabeled_seconds_count{} 1
+`
+
+	buf := &bytes.Buffer{}
+	r.write(buf)
+
+	if got := buf.String(); got != exp {
+		t.Errorf("got output:\n%s\nexpected:\n%s", got, exp)
+	}
+}
@@ -0,0 +1,387 @@
+package monitoring
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset/typed/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	informersCoreV1 "k8s.io/client-go/informers/core/v1"
+	listersCoreV1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Reasons of the broker Ready condition set by the Service Catalog controller-manager when the relist fails
+const (
+	reasonErrorFetchingCatalog = "ErrorFetchingCatalog"
+	reasonErrorSyncingCatalog  = "ErrorSyncingCatalog"
+)
+
+// BrokerMonitorConfig holds configuration for the BrokerMonitor
+type BrokerMonitorConfig struct {
+	Enabled bool `envconfig:"default=true"`
+	// CatalogCheckInterval defines how often the number of classes and plans offered by brokers is checked
+	CatalogCheckInterval time.Duration `envconfig:"default=1m"`
+	// MaxCatalogDrop is the fraction of classes or plans which can disappear from the baseline without the notification
+	MaxCatalogDrop float64 `envconfig:"default=0.2"`
+}
+
+// BrokerMonitor watches ClusterServiceBrokers and ServiceBrokers and sends notification when:
+// - the broker Ready condition turns false, including the failed relist
+// - the number of classes or plans offered by the broker drops by more than the configured fraction of the baseline
+//
+// The notification is sent again only after the broker recovers. The catalog baseline is the highest number
+// of classes and plans seen since the last catalog notification, so also a slow shrinking is reported.
+// Brokers which are being deleted and brokers in namespaces created by tests are skipped, as tests report their failures.
+type BrokerMonitor struct {
+	cfg           BrokerMonitorConfig
+	slackNotifier SlackNotifier
+	log           logrus.FieldLogger
+
+	clusterBrokers   cache.Indexer
+	brokers          cache.Indexer
+	brokerInformers  []cache.SharedIndexInformer
+	catalogInformers []cache.SharedIndexInformer
+	classes          cache.Indexer
+	plans            cache.Indexer
+	clusterClasses   cache.Indexer
+	clusterPlans     cache.Indexer
+	namespaces       listersCoreV1.NamespaceLister
+	namespacesSync   cache.InformerSynced
+
+	mu sync.Mutex
+	// notReady holds brokers which were reported as not ready
+	notReady map[brokerRef]struct{}
+	// baselines holds the number of classes and plans of each broker to which the current catalog is compared
+	baselines map[brokerRef]catalogSize
+}
+
+type catalogSize struct {
+	classes int
+	plans   int
+}
+
+// notification is built while holding the lock and sent after it is released, so Slack calls do not block other checks
+type notification struct {
+	ref     brokerRef
+	header  string
+	details string
+	size    catalogSize
+}
+
+// NewBrokerMonitor returns new instance of the BrokerMonitor
+func NewBrokerMonitor(cfg BrokerMonitorConfig, scCli scClient.ServicecatalogV1beta1Interface, nsInformer informersCoreV1.NamespaceInformer, slackNotifier SlackNotifier, resync time.Duration, log logrus.FieldLogger) *BrokerMonitor {
+	m := &BrokerMonitor{
+		cfg:            cfg,
+		slackNotifier:  slackNotifier,
+		log:            log.WithField("service", "monitoring:broker-monitor"),
+		namespaces:     nsInformer.Lister(),
+		namespacesSync: nsInformer.Informer().HasSynced,
+		notReady:       map[brokerRef]struct{}{},
+		baselines:      map[brokerRef]catalogSize{},
+	}
+
+	restCli := scCli.RESTClient()
+	clusterBrokers := newInformer(restCli, "clusterservicebrokers", &scTypes.ClusterServiceBroker{}, resync)
+	brokers := newInformer(restCli, "servicebrokers", &scTypes.ServiceBroker{}, resync)
+	clusterClasses := newInformer(restCli, "clusterserviceclasses", &scTypes.ClusterServiceClass{}, resync)
+	clusterPlans := newInformer(restCli, "clusterserviceplans", &scTypes.ClusterServicePlan{}, resync)
+	classes := newInformer(restCli, "serviceclasses", &scTypes.ServiceClass{}, resync)
+	plans := newInformer(restCli, "serviceplans", &scTypes.ServicePlan{}, resync)
+
+	m.brokerInformers = []cache.SharedIndexInformer{clusterBrokers, brokers}
+	m.clusterBrokers, m.brokers = clusterBrokers.GetIndexer(), brokers.GetIndexer()
+	m.catalogInformers = []cache.SharedIndexInformer{clusterClasses, clusterPlans, classes, plans}
+	m.clusterClasses, m.clusterPlans = clusterClasses.GetIndexer(), clusterPlans.GetIndexer()
+	m.classes, m.plans = classes.GetIndexer(), plans.GetIndexer()
+
+	return m
+}
+
+// newInformer returns the informer of given Service Catalog resource from all namespaces
+func newInformer(restCli cache.Getter, resource string, objType runtime.Object, resync time.Duration) cache.SharedIndexInformer {
+	lw := cache.NewListWatchFromClient(restCli, resource, metav1.NamespaceAll, fields.Everything())
+	return cache.NewSharedIndexInformer(lw, objType, resync, cache.Indexers{})
+}
+
+// Run starts informers and checks brokers until stop channel is closed.
+// The Namespace informer is started by its factory.
+func (m *BrokerMonitor) Run(stopCh <-chan struct{}) {
+	for _, inf := range m.brokerInformers {
+		inf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    m.checkBroker,
+			UpdateFunc: func(oldObj, newObj interface{}) { m.checkBroker(newObj) },
+			DeleteFunc: m.forgetBroker,
+		})
+	}
+
+	synced := []cache.InformerSynced{m.namespacesSync}
+	for _, inf := range append(m.brokerInformers, m.catalogInformers...) {
+		go inf.Run(stopCh)
+		synced = append(synced, inf.HasSynced)
+	}
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		m.log.Error("Cannot sync Service Catalog informers caches")
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.CatalogCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.checkCatalogs()
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *BrokerMonitor) checkBroker(obj interface{}) {
+	var (
+		ref    brokerRef
+		meta   metav1.ObjectMeta
+		status scTypes.CommonServiceBrokerStatus
+	)
+	switch broker := obj.(type) {
+	case *scTypes.ClusterServiceBroker:
+		ref, meta, status = clusterBrokerRef(broker.Name), broker.ObjectMeta, broker.Status.CommonServiceBrokerStatus
+	case *scTypes.ServiceBroker:
+		ref, meta, status = brokerRefOf(broker.Namespace, broker.Name), broker.ObjectMeta, broker.Status.CommonServiceBrokerStatus
+	default:
+		m.log.Warnf("while checking broker: cannot covert obj [%+v] of type %T to broker", obj, obj)
+		return
+	}
+	if meta.DeletionTimestamp != nil || m.skipNamespace(meta.Namespace) {
+		return
+	}
+
+	var ready *scTypes.ServiceBrokerCondition
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == scTypes.ServiceBrokerConditionReady {
+			ready = &status.Conditions[i]
+		}
+	}
+	if ready == nil {
+		// not processed by the controller-manager yet
+		return
+	}
+
+	m.mu.Lock()
+	_, reported := m.notReady[ref]
+	var n *notification
+	switch {
+	case ready.Status == scTypes.ConditionTrue && reported:
+		delete(m.notReady, ref)
+		n = &notification{ref: ref, header: fmt.Sprintf("*[Phase: MONITORING]* _Broker %s recovered_", ref), details: "Ready condition is True"}
+	case ready.Status != scTypes.ConditionTrue && !reported:
+		// marked before sending, so the concurrent update of the broker is not reported twice
+		m.notReady[ref] = struct{}{}
+		n = &notification{ref: ref, header: fmt.Sprintf("*[Phase: MONITORING]* _Broker %s is not ready_", ref)}
+		if ready.Reason == reasonErrorFetchingCatalog || ready.Reason == reasonErrorSyncingCatalog {
+			n.header = fmt.Sprintf("*[Phase: MONITORING]* _Relist of broker %s failed_", ref)
+		}
+		n.details = fmt.Sprintf("Ready condition is %s since %v, reason: %s, message: %s", ready.Status, ready.LastTransitionTime, ready.Reason, ready.Message)
+		if status.LastCatalogRetrievalTime != nil {
+			n.details += fmt.Sprintf("\nLast catalog retrieval: %v", status.LastCatalogRetrievalTime)
+		}
+	}
+	m.mu.Unlock()
+
+	if n == nil {
+		return
+	}
+	if !m.notify(*n) && !reported {
+		// not reported, so the notification is sent again on the next update
+		m.mu.Lock()
+		delete(m.notReady, ref)
+		m.mu.Unlock()
+	}
+}
+
+func (m *BrokerMonitor) forgetBroker(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	var ref brokerRef
+	switch broker := obj.(type) {
+	case *scTypes.ClusterServiceBroker:
+		ref = clusterBrokerRef(broker.Name)
+	case *scTypes.ServiceBroker:
+		ref = brokerRefOf(broker.Namespace, broker.Name)
+	default:
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.notReady, ref)
+	delete(m.baselines, ref)
+}
+
+// checkCatalogs compares the number of classes and plans offered by each broker with its baseline.
+// The baseline is kept until the notification is sent or the catalog recovers, so it cannot shrink
+// in steps which are smaller than the allowed drop.
+func (m *BrokerMonitor) checkCatalogs() {
+	current := map[brokerRef]catalogSize{}
+	count := func(ref brokerRef, class bool) {
+		size := current[ref]
+		if class {
+			size.classes++
+		} else {
+			size.plans++
+		}
+		current[ref] = size
+	}
+
+	for _, obj := range m.clusterClasses.List() {
+		if c, ok := obj.(*scTypes.ClusterServiceClass); ok && !c.Status.RemovedFromBrokerCatalog {
+			count(clusterBrokerRef(c.Spec.ClusterServiceBrokerName), true)
+		}
+	}
+	for _, obj := range m.clusterPlans.List() {
+		if p, ok := obj.(*scTypes.ClusterServicePlan); ok && !p.Status.RemovedFromBrokerCatalog {
+			count(clusterBrokerRef(p.Spec.ClusterServiceBrokerName), false)
+		}
+	}
+	for _, obj := range m.classes.List() {
+		if c, ok := obj.(*scTypes.ServiceClass); ok && !c.Status.RemovedFromBrokerCatalog {
+			count(brokerRefOf(c.Namespace, c.Spec.ServiceBrokerName), true)
+		}
+	}
+	for _, obj := range m.plans.List() {
+		if p, ok := obj.(*scTypes.ServicePlan); ok && !p.Status.RemovedFromBrokerCatalog {
+			count(brokerRefOf(p.Namespace, p.Spec.ServiceBrokerName), false)
+		}
+	}
+
+	var notifications []notification
+	m.mu.Lock()
+	for ref, baseline := range m.baselines {
+		if !m.monitored(ref) {
+			delete(m.baselines, ref)
+			continue
+		}
+
+		now := current[ref]
+		if m.dropped(baseline.classes, now.classes) || m.dropped(baseline.plans, now.plans) {
+			notifications = append(notifications, notification{
+				ref:     ref,
+				header:  fmt.Sprintf("*[Phase: MONITORING]* _Catalog of broker %s shrank_", ref),
+				details: fmt.Sprintf("Classes: %d -> %d, plans: %d -> %d", baseline.classes, now.classes, baseline.plans, now.plans),
+				size:    now,
+			})
+		}
+	}
+	for ref, now := range current {
+		if !m.monitored(ref) {
+			continue
+		}
+		// the baseline follows only the growing catalog, the drop is accepted after it is reported
+		baseline, found := m.baselines[ref]
+		if !found || now.classes > baseline.classes {
+			baseline.classes = now.classes
+		}
+		if !found || now.plans > baseline.plans {
+			baseline.plans = now.plans
+		}
+		m.baselines[ref] = baseline
+	}
+	m.mu.Unlock()
+
+	for _, n := range notifications {
+		if !m.notify(n) {
+			continue
+		}
+		m.mu.Lock()
+		if _, found := m.baselines[n.ref]; found {
+			m.baselines[n.ref] = n.size
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *BrokerMonitor) dropped(prev, now int) bool {
+	return prev > 0 && now < prev && float64(prev-now)/float64(prev) > m.cfg.MaxCatalogDrop
+}
+
+// monitored returns true when the broker exists, is not being deleted and is not in the namespace created by tests
+func (m *BrokerMonitor) monitored(ref brokerRef) bool {
+	var (
+		obj    interface{}
+		exists bool
+	)
+	if ref.namespace == "" {
+		obj, exists, _ = m.clusterBrokers.GetByKey(ref.name)
+	} else {
+		obj, exists, _ = m.brokers.GetByKey(ref.namespace + "/" + ref.name)
+	}
+	if !exists {
+		return false
+	}
+
+	switch broker := obj.(type) {
+	case *scTypes.ClusterServiceBroker:
+		return broker.DeletionTimestamp == nil
+	case *scTypes.ServiceBroker:
+		return broker.DeletionTimestamp == nil && !m.skipNamespace(broker.Namespace)
+	default:
+		return false
+	}
+}
+
+func (m *BrokerMonitor) skipNamespace(name string) bool {
+	if name == "" {
+		return false
+	}
+	ns, err := m.namespaces.Get(name)
+	if err != nil {
+		return false
+	}
+	return ns.Labels[labels.TestNamespace] == "true"
+}
+
+// notify sends the notification and returns true when it was sent. It must not be called while holding the lock.
+func (m *BrokerMonitor) notify(n notification) bool {
+	id := uuid.NewV4().String()
+	log := m.log.WithField("ID", id)
+	log.Infof("%s: %s", n.ref, n.details)
+
+	if err := m.slackNotifier.Notify(id, n.header, n.details); err != nil {
+		log.Errorf("Got error while sending notification to Slack: %v", err)
+		return false
+	}
+	return true
+}
+
+// brokerRef identifies the ClusterServiceBroker, when namespace is empty, or the ServiceBroker
+type brokerRef struct {
+	namespace string
+	name      string
+}
+
+func clusterBrokerRef(name string) brokerRef {
+	return brokerRef{name: name}
+}
+
+func brokerRefOf(namespace, name string) brokerRef {
+	return brokerRef{namespace: namespace, name: name}
+}
+
+// String returns the reference under which the broker is reported, e.g. `ClusterServiceBroker helm-broker`
+func (r brokerRef) String() string {
+	if r.namespace == "" {
+		return "ClusterServiceBroker " + r.name
+	}
+	return fmt.Sprintf("ServiceBroker %s/%s", r.namespace, r.name)
+}
@@ -0,0 +1,270 @@
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	"github.com/sirupsen/logrus"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listersCoreV1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const testNamespace = "test-ns"
+
+func TestBrokerMonitorDropped(t *testing.T) {
+	m := &BrokerMonitor{cfg: BrokerMonitorConfig{MaxCatalogDrop: 0.2}}
+
+	tests := map[string]struct {
+		prev, now int
+		exp       bool
+	}{
+		"no baseline":        {prev: 0, now: 0, exp: false},
+		"grown":              {prev: 10, now: 12, exp: false},
+		"unchanged":          {prev: 10, now: 10, exp: false},
+		"dropped by allowed": {prev: 10, now: 8, exp: false},
+		"dropped by more":    {prev: 10, now: 7, exp: true},
+		"dropped to zero":    {prev: 1, now: 0, exp: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := m.dropped(tc.prev, tc.now); got != tc.exp {
+				t.Errorf("got dropped(%d, %d) = %v, expected %v", tc.prev, tc.now, got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestBrokerMonitorCheckCatalogs(t *testing.T) {
+	notifier := &fakeNotifier{}
+	m := newTestBrokerMonitor(notifier)
+	ref := clusterBrokerRef("helm-broker")
+	m.clusterBrokers.Add(&scTypes.ClusterServiceBroker{ObjectMeta: metav1.ObjectMeta{Name: ref.name}})
+
+	steps := []struct {
+		classes     int
+		notifyErr   error
+		expNotify   bool
+		expBaseline int
+	}{
+		{classes: 10, expNotify: false, expBaseline: 10},
+		// the baseline is kept, so the slow shrinking is reported
+		{classes: 9, expNotify: false, expBaseline: 10},
+		{classes: 8, expNotify: false, expBaseline: 10},
+		{classes: 7, expNotify: true, expBaseline: 7},
+		{classes: 7, expNotify: false, expBaseline: 7},
+		{classes: 12, expNotify: false, expBaseline: 12},
+		// the baseline is kept when the notification is not sent, so it is sent again
+		{classes: 9, notifyErr: errors.New("slack is down"), expNotify: true, expBaseline: 12},
+		{classes: 9, expNotify: true, expBaseline: 9},
+	}
+
+	for i, s := range steps {
+		setCatalog(m, ref, s.classes)
+		notifier.calls, notifier.err = nil, s.notifyErr
+
+		m.checkCatalogs()
+
+		if notified := len(notifier.calls) > 0; notified != s.expNotify {
+			t.Fatalf("step %d: got notified %v, expected %v, notifications: %v", i, notified, s.expNotify, notifier.calls)
+		}
+		if s.expNotify && !strings.Contains(notifier.calls[0], "Catalog of broker ClusterServiceBroker helm-broker shrank") {
+			t.Fatalf("step %d: got notification %q, expected catalog notification", i, notifier.calls[0])
+		}
+		exp := catalogSize{classes: s.expBaseline, plans: s.expBaseline}
+		if got := m.baselines[ref]; got != exp {
+			t.Fatalf("step %d: got baseline %+v, expected %+v", i, got, exp)
+		}
+	}
+}
+
+func TestBrokerMonitorCheckCatalogsSkipsBrokers(t *testing.T) {
+	notifier := &fakeNotifier{}
+	m := newTestBrokerMonitor(notifier)
+
+	deleting, inTestNs, monitored := clusterBrokerRef("deleting"), brokerRefOf(testNamespace, "test"), brokerRefOf("default", "user")
+	m.clusterBrokers.Add(&scTypes.ClusterServiceBroker{ObjectMeta: metav1.ObjectMeta{Name: deleting.name, DeletionTimestamp: &metav1.Time{}}})
+	m.brokers.Add(&scTypes.ServiceBroker{ObjectMeta: metav1.ObjectMeta{Namespace: inTestNs.namespace, Name: inTestNs.name}})
+	m.brokers.Add(&scTypes.ServiceBroker{ObjectMeta: metav1.ObjectMeta{Namespace: monitored.namespace, Name: monitored.name}})
+	// the baseline recorded before the broker deletion started
+	m.baselines[deleting] = catalogSize{classes: 10, plans: 10}
+
+	// catalogs of brokers which are being deleted are removed
+	setCatalog(m, deleting, 0)
+	setCatalog(m, inTestNs, 10)
+	setCatalog(m, monitored, 10)
+
+	m.checkCatalogs()
+
+	if len(notifier.calls) > 0 {
+		t.Errorf("got notifications %v, expected none", notifier.calls)
+	}
+	if len(m.baselines) != 1 || m.baselines[monitored] != (catalogSize{classes: 10, plans: 10}) {
+		t.Errorf("got baselines %v, expected only the baseline of %s", m.baselines, monitored)
+	}
+}
+
+func TestBrokerMonitorCheckBroker(t *testing.T) {
+	notReady := scTypes.ServiceBrokerCondition{Type: scTypes.ServiceBrokerConditionReady, Status: scTypes.ConditionFalse, Reason: "ErrorCallingBroker"}
+	relistFailed := scTypes.ServiceBrokerCondition{Type: scTypes.ServiceBrokerConditionReady, Status: scTypes.ConditionFalse, Reason: reasonErrorFetchingCatalog}
+	ready := scTypes.ServiceBrokerCondition{Type: scTypes.ServiceBrokerConditionReady, Status: scTypes.ConditionTrue}
+
+	clusterBroker := func(cond scTypes.ServiceBrokerCondition) interface{} {
+		b := &scTypes.ClusterServiceBroker{ObjectMeta: metav1.ObjectMeta{Name: "helm-broker"}}
+		b.Status.Conditions = []scTypes.ServiceBrokerCondition{cond}
+		return b
+	}
+	broker := func(namespace string, cond scTypes.ServiceBrokerCondition) interface{} {
+		b := &scTypes.ServiceBroker{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "ns-broker"}}
+		b.Status.Conditions = []scTypes.ServiceBrokerCondition{cond}
+		return b
+	}
+	deleting := func(cond scTypes.ServiceBrokerCondition) interface{} {
+		b := clusterBroker(cond).(*scTypes.ClusterServiceBroker)
+		b.DeletionTimestamp = &metav1.Time{}
+		return b
+	}
+
+	tests := map[string]struct {
+		updates []interface{}
+		// failedCalls is the number of first notifications which fail
+		failedCalls int
+		exp         []string
+	}{
+		"not ready broker is reported once until it recovers": {
+			updates: []interface{}{clusterBroker(ready), clusterBroker(notReady), clusterBroker(notReady), clusterBroker(ready), clusterBroker(ready)},
+			exp:     []string{"Broker ClusterServiceBroker helm-broker is not ready", "Broker ClusterServiceBroker helm-broker recovered"},
+		},
+		"failed relist is reported": {
+			updates: []interface{}{broker("default", relistFailed)},
+			exp:     []string{"Relist of broker ServiceBroker default/ns-broker failed"},
+		},
+		"failed notification is sent again": {
+			updates:     []interface{}{clusterBroker(notReady), clusterBroker(notReady), clusterBroker(notReady)},
+			failedCalls: 1,
+			exp:         []string{"Broker ClusterServiceBroker helm-broker is not ready", "Broker ClusterServiceBroker helm-broker is not ready"},
+		},
+		"broker which is being deleted is skipped": {
+			updates: []interface{}{deleting(notReady)},
+		},
+		"broker in test namespace is skipped": {
+			updates: []interface{}{broker(testNamespace, notReady)},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			notifier := &fakeNotifier{failedCalls: tc.failedCalls}
+			m := newTestBrokerMonitor(notifier)
+
+			for _, obj := range tc.updates {
+				m.checkBroker(obj)
+			}
+
+			if len(notifier.calls) != len(tc.exp) {
+				t.Fatalf("got notifications %v, expected %v", notifier.calls, tc.exp)
+			}
+			for i, exp := range tc.exp {
+				if !strings.Contains(notifier.calls[i], exp) {
+					t.Errorf("got notification %q, expected %q", notifier.calls[i], exp)
+				}
+			}
+		})
+	}
+}
+
+// newTestBrokerMonitor returns the BrokerMonitor with empty caches and the test namespace
+func newTestBrokerMonitor(notifier SlackNotifier) *BrokerMonitor {
+	log := logrus.New()
+	log.Out = ioutil.Discard
+
+	newIndexer := func() cache.Indexer {
+		return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	}
+	namespaces := newIndexer()
+	namespaces.Add(&k8sCoreTypes.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace, Labels: map[string]string{labels.TestNamespace: "true"}}})
+	namespaces.Add(&k8sCoreTypes.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+
+	return &BrokerMonitor{
+		cfg:            BrokerMonitorConfig{MaxCatalogDrop: 0.2},
+		slackNotifier:  notifier,
+		log:            log,
+		clusterBrokers: newIndexer(),
+		brokers:        newIndexer(),
+		clusterClasses: newIndexer(),
+		clusterPlans:   newIndexer(),
+		classes:        newIndexer(),
+		plans:          newIndexer(),
+		namespaces:     listersCoreV1.NewNamespaceLister(namespaces),
+		notReady:       map[brokerRef]struct{}{},
+		baselines:      map[brokerRef]catalogSize{},
+	}
+}
+
+// setCatalog replaces classes and plans of given broker with the given number of classes, each with one plan
+func setCatalog(m *BrokerMonitor, ref brokerRef, classes int) {
+	for _, obj := range append(m.clusterClasses.List(), append(m.clusterPlans.List(), append(m.classes.List(), m.plans.List()...)...)...) {
+		switch o := obj.(type) {
+		case *scTypes.ClusterServiceClass:
+			if o.Spec.ClusterServiceBrokerName == ref.name && ref.namespace == "" {
+				m.clusterClasses.Delete(o)
+			}
+		case *scTypes.ClusterServicePlan:
+			if o.Spec.ClusterServiceBrokerName == ref.name && ref.namespace == "" {
+				m.clusterPlans.Delete(o)
+			}
+		case *scTypes.ServiceClass:
+			if o.Spec.ServiceBrokerName == ref.name && o.Namespace == ref.namespace {
+				m.classes.Delete(o)
+			}
+		case *scTypes.ServicePlan:
+			if o.Spec.ServiceBrokerName == ref.name && o.Namespace == ref.namespace {
+				m.plans.Delete(o)
+			}
+		}
+	}
+
+	for i := 0; i < classes; i++ {
+		meta := metav1.ObjectMeta{Namespace: ref.namespace, Name: fmt.Sprintf("%s-%d", ref.name, i)}
+		if ref.namespace == "" {
+			class := &scTypes.ClusterServiceClass{ObjectMeta: meta}
+			class.Spec.ClusterServiceBrokerName = ref.name
+			plan := &scTypes.ClusterServicePlan{ObjectMeta: meta}
+			plan.Spec.ClusterServiceBrokerName = ref.name
+			m.clusterClasses.Add(class)
+			m.clusterPlans.Add(plan)
+			continue
+		}
+
+		class := &scTypes.ServiceClass{ObjectMeta: meta}
+		class.Spec.ServiceBrokerName = ref.name
+		plan := &scTypes.ServicePlan{ObjectMeta: meta}
+		plan.Spec.ServiceBrokerName = ref.name
+		m.classes.Add(class)
+		m.plans.Add(plan)
+	}
+}
+
+// fakeNotifier records headers of sent notifications
+type fakeNotifier struct {
+	calls []string
+	// err is returned from all calls, failedCalls is the number of first calls which fail
+	err         error
+	failedCalls int
+}
+
+func (n *fakeNotifier) Notify(_, header, _ string) error {
+	n.calls = append(n.calls, header)
+	if n.failedCalls > 0 {
+		n.failedCalls--
+		return errors.New("cannot send notification")
+	}
+	return n.err
+}
@@ -0,0 +1,365 @@
+package monitoring
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset/typed/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	informersCoreV1 "k8s.io/client-go/informers/core/v1"
+	listersCoreV1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxReportedPerOffering limits the number of resources listed in the notification for a single class and plan
+const maxReportedPerOffering = 5
+
+// FailedResourceDetectorConfig holds configuration for the FailedResourceDetector
+type FailedResourceDetectorConfig struct {
+	Enabled bool `envconfig:"default=false"`
+	// Interval defines how often ServiceInstances and ServiceBindings are checked
+	Interval time.Duration `envconfig:"default=5m"`
+	// UnreadyThreshold defines the time after which the not ready ServiceInstance or ServiceBinding is reported
+	UnreadyThreshold time.Duration `envconfig:"default=30m"`
+}
+
+// FailedResourceDetector periodically checks ServiceInstances and ServiceBindings in all namespaces, as users' resources
+// can fail while tests pass. Resources which are failed, in the orphan mitigation or not ready longer than the threshold
+// are reported, aggregated by the broker, class and plan. Each problem is reported once, until the resource recovers.
+// Namespaces created by tests and the soak test namespace are skipped, as tests report their failures.
+type FailedResourceDetector struct {
+	cfg           FailedResourceDetectorConfig
+	slackNotifier SlackNotifier
+	log           logrus.FieldLogger
+
+	informers      []cache.SharedIndexInformer
+	namespacesSync cache.InformerSynced
+	instances      cache.Indexer
+	bindings       cache.Indexer
+	clusterClasses cache.Indexer
+	classes        cache.Indexer
+	namespaces     listersCoreV1.NamespaceLister
+
+	// reported holds keys of problems which were already reported
+	reported map[string]struct{}
+}
+
+// resourceProblem describes the problem of a single ServiceInstance or ServiceBinding
+type resourceProblem struct {
+	kind      string
+	namespace string
+	name      string
+	// problem is the stable category of the problem, e.g. `Failed`
+	problem string
+	details string
+}
+
+func (p resourceProblem) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", p.kind, p.namespace, p.name, p.problem)
+}
+
+// offering identifies the broker, class and plan by which problems are aggregated
+type offering struct {
+	broker string
+	class  string
+	plan   string
+}
+
+// condition is the common representation of ServiceInstance and ServiceBinding conditions
+type condition struct {
+	conditionType      string
+	status             scTypes.ConditionStatus
+	reason             string
+	message            string
+	lastTransitionTime metav1.Time
+}
+
+// NewFailedResourceDetector returns new instance of the FailedResourceDetector
+func NewFailedResourceDetector(cfg FailedResourceDetectorConfig, scCli scClient.ServicecatalogV1beta1Interface, nsInformer informersCoreV1.NamespaceInformer, slackNotifier SlackNotifier, resync time.Duration, log logrus.FieldLogger) *FailedResourceDetector {
+	restCli := scCli.RESTClient()
+	instances := newInformer(restCli, "serviceinstances", &scTypes.ServiceInstance{}, resync)
+	bindings := newInformer(restCli, "servicebindings", &scTypes.ServiceBinding{}, resync)
+	clusterClasses := newInformer(restCli, "clusterserviceclasses", &scTypes.ClusterServiceClass{}, resync)
+	classes := newInformer(restCli, "serviceclasses", &scTypes.ServiceClass{}, resync)
+
+	return &FailedResourceDetector{
+		cfg:            cfg,
+		slackNotifier:  slackNotifier,
+		log:            log.WithField("service", "monitoring:failed-resource-detector"),
+		informers:      []cache.SharedIndexInformer{instances, bindings, clusterClasses, classes},
+		instances:      instances.GetIndexer(),
+		bindings:       bindings.GetIndexer(),
+		clusterClasses: clusterClasses.GetIndexer(),
+		classes:        classes.GetIndexer(),
+		namespaces:     nsInformer.Lister(),
+		namespacesSync: nsInformer.Informer().HasSynced,
+		reported:       map[string]struct{}{},
+	}
+}
+
+// Run starts informers and checks ServiceInstances and ServiceBindings in a loop until stop channel is closed.
+// The Namespace informer is started by its factory.
+func (d *FailedResourceDetector) Run(stopCh <-chan struct{}) {
+	synced := []cache.InformerSynced{d.namespacesSync}
+	for _, inf := range d.informers {
+		go inf.Run(stopCh)
+		synced = append(synced, inf.HasSynced)
+	}
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		d.log.Error("Cannot sync Service Catalog informers caches")
+		return
+	}
+
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.check()
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *FailedResourceDetector) check() {
+	now := time.Now()
+	problems := map[offering][]resourceProblem{}
+	current := map[string]struct{}{}
+	var found []resourceProblem
+
+	for _, obj := range d.instances.List() {
+		si, ok := obj.(*scTypes.ServiceInstance)
+		if !ok || d.skipNamespace(si.Namespace) {
+			continue
+		}
+		p, failing := d.problemOf("ServiceInstance", si.ObjectMeta, instanceConditions(si), si.Status.OrphanMitigationInProgress, now)
+		if !failing {
+			continue
+		}
+		current[p.key()] = struct{}{}
+		if _, reported := d.reported[p.key()]; !reported {
+			o := d.offeringOf(si)
+			problems[o] = append(problems[o], p)
+			found = append(found, p)
+		}
+	}
+
+	for _, obj := range d.bindings.List() {
+		b, ok := obj.(*scTypes.ServiceBinding)
+		if !ok || d.skipNamespace(b.Namespace) {
+			continue
+		}
+		p, failing := d.problemOf("ServiceBinding", b.ObjectMeta, bindingConditions(b), b.Status.OrphanMitigationInProgress, now)
+		if !failing {
+			continue
+		}
+		current[p.key()] = struct{}{}
+		if _, reported := d.reported[p.key()]; !reported {
+			o := d.bindingOffering(b)
+			problems[o] = append(problems[o], p)
+			found = append(found, p)
+		}
+	}
+
+	// forget problems which are gone, so they are reported again when they come back
+	for key := range d.reported {
+		if _, stillFailing := current[key]; !stillFailing {
+			delete(d.reported, key)
+		}
+	}
+
+	if len(found) == 0 {
+		return
+	}
+	if err := d.notify(problems, len(found)); err != nil {
+		d.log.Errorf("Got error while sending notification to Slack: %v", err)
+		return
+	}
+	for _, p := range found {
+		d.reported[p.key()] = struct{}{}
+	}
+}
+
+// problemOf returns the problem of the resource and true, or false when the resource is fine
+func (d *FailedResourceDetector) problemOf(kind string, meta metav1.ObjectMeta, conditions []condition, orphanMitigation bool, now time.Time) (resourceProblem, bool) {
+	p := resourceProblem{kind: kind, namespace: meta.Namespace, name: meta.Name}
+
+	var ready *condition
+	for i, c := range conditions {
+		switch {
+		case c.conditionType == "Failed" && c.status == scTypes.ConditionTrue:
+			p.problem, p.details = "Failed", fmt.Sprintf("reason: %s, message: %s", c.reason, c.message)
+			return p, true
+		case c.conditionType == "Ready":
+			ready = &conditions[i]
+		}
+	}
+
+	if orphanMitigation {
+		p.problem, p.details = "OrphanMitigationInProgress", "orphan mitigation in progress"
+		return p, true
+	}
+
+	if ready != nil && ready.status == scTypes.ConditionTrue {
+		return p, false
+	}
+	since := meta.CreationTimestamp.Time
+	if ready != nil && !ready.lastTransitionTime.IsZero() {
+		since = ready.lastTransitionTime.Time
+	}
+	if unready := now.Sub(since); unready > d.cfg.UnreadyThreshold {
+		p.problem, p.details = "Unready", fmt.Sprintf("not ready for %v", unready.Round(time.Minute))
+		if ready != nil {
+			p.details += fmt.Sprintf(", reason: %s, message: %s", ready.reason, ready.message)
+		}
+		return p, true
+	}
+
+	return p, false
+}
+
+func (d *FailedResourceDetector) skipNamespace(name string) bool {
+	ns, err := d.namespaces.Get(name)
+	if err != nil {
+		return false
+	}
+	return ns.Labels[labels.TestNamespace] == "true" || ns.Labels[labels.SoakNamespace] == "true"
+}
+
+// offeringOf returns the broker, class and plan of the ServiceInstance. External names are preferred,
+// when the instance references them by Kubernetes names, those are used instead.
+func (d *FailedResourceDetector) offeringOf(si *scTypes.ServiceInstance) offering {
+	o := offering{
+		class: firstNonEmpty(si.Spec.ClusterServiceClassExternalName, si.Spec.ServiceClassExternalName, si.Spec.ClusterServiceClassName, si.Spec.ServiceClassName),
+		plan:  firstNonEmpty(si.Spec.ClusterServicePlanExternalName, si.Spec.ServicePlanExternalName, si.Spec.ClusterServicePlanName, si.Spec.ServicePlanName),
+	}
+
+	switch {
+	case si.Spec.ClusterServiceClassRef != nil:
+		if obj, exists, _ := d.clusterClasses.GetByKey(si.Spec.ClusterServiceClassRef.Name); exists {
+			if class, ok := obj.(*scTypes.ClusterServiceClass); ok {
+				o.broker = clusterBrokerRef(class.Spec.ClusterServiceBrokerName).String()
+				o.class = class.Spec.ExternalName
+			}
+		}
+	case si.Spec.ServiceClassRef != nil:
+		if obj, exists, _ := d.classes.GetByKey(si.Namespace + "/" + si.Spec.ServiceClassRef.Name); exists {
+			if class, ok := obj.(*scTypes.ServiceClass); ok {
+				o.broker = brokerRefOf(class.Namespace, class.Spec.ServiceBrokerName).String()
+				o.class = class.Spec.ExternalName
+			}
+		}
+	}
+	if props := si.Status.ExternalProperties; props != nil {
+		o.plan = firstNonEmpty(props.ClusterServicePlanExternalName, props.ServicePlanExternalName, o.plan)
+	}
+
+	return o
+}
+
+// bindingOffering returns the offering of the ServiceInstance referenced by the ServiceBinding
+func (d *FailedResourceDetector) bindingOffering(b *scTypes.ServiceBinding) offering {
+	obj, exists, _ := d.instances.GetByKey(b.Namespace + "/" + b.Spec.ServiceInstanceRef.Name)
+	if !exists {
+		return offering{}
+	}
+	si, ok := obj.(*scTypes.ServiceInstance)
+	if !ok {
+		return offering{}
+	}
+	return d.offeringOf(si)
+}
+
+func (d *FailedResourceDetector) notify(problems map[offering][]resourceProblem, count int) error {
+	offerings := make([]offering, 0, len(problems))
+	for o := range problems {
+		offerings = append(offerings, o)
+	}
+	sort.Slice(offerings, func(i, k int) bool {
+		return fmt.Sprint(offerings[i]) < fmt.Sprint(offerings[k])
+	})
+
+	details := &bytes.Buffer{}
+	for _, o := range offerings {
+		ps := problems[o]
+		sort.Slice(ps, func(i, k int) bool { return ps[i].key() < ps[k].key() })
+
+		fmt.Fprintf(details, "• Broker *%s*, class *%s*, plan *%s*: %s\n", orUnknown(o.broker), orUnknown(o.class), orUnknown(o.plan), countByProblem(ps))
+		for i, p := range ps {
+			if i == maxReportedPerOffering {
+				fmt.Fprintf(details, "    ... and %d more\n", len(ps)-maxReportedPerOffering)
+				break
+			}
+			fmt.Fprintf(details, "    %s %s/%s [%s]: %s\n", p.kind, p.namespace, p.name, p.problem, p.details)
+		}
+	}
+
+	id := uuid.NewV4().String()
+	d.log.WithField("ID", id).Infof("Found %d failing ServiceInstances and ServiceBindings:\n%s", count, details.String())
+
+	header := fmt.Sprintf("*[Phase: MONITORING]* _Found %d failing ServiceInstances and ServiceBindings_", count)
+	return d.slackNotifier.Notify(id, header, details.String())
+}
+
+func countByProblem(ps []resourceProblem) string {
+	counts := map[string]int{}
+	var keys []string
+	for _, p := range ps {
+		k := fmt.Sprintf("%s %s", p.problem, p.kind)
+		if counts[k] == 0 {
+			keys = append(keys, k)
+		}
+		counts[k]++
+	}
+	sort.Strings(keys)
+
+	out := &bytes.Buffer{}
+	for i, k := range keys {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		fmt.Fprintf(out, "%d %s", counts[k], k)
+	}
+	return out.String()
+}
+
+func instanceConditions(si *scTypes.ServiceInstance) []condition {
+	out := make([]condition, 0, len(si.Status.Conditions))
+	for _, c := range si.Status.Conditions {
+		out = append(out, condition{string(c.Type), c.Status, c.Reason, c.Message, c.LastTransitionTime})
+	}
+	return out
+}
+
+func bindingConditions(b *scTypes.ServiceBinding) []condition {
+	out := make([]condition, 0, len(b.Status.Conditions))
+	for _, c := range b.Status.Conditions {
+		out = append(out, condition{string(c.Type), c.Status, c.Reason, c.Message, c.LastTransitionTime})
+	}
+	return out
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package osb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ClientConfig holds configuration of the Open Service Broker API client
+type ClientConfig struct {
+	URL      string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// Client is a raw Open Service Broker API client. It does not interpret the responses,
+// so it can be used to verify how the broker fulfills the API contract.
+type Client struct {
+	cfg     ClientConfig
+	httpCli *http.Client
+}
+
+// Response is the raw broker response
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// NewClient returns new instance of the Client
+func NewClient(cfg ClientConfig) *Client {
+	return &Client{
+		cfg:     cfg,
+		httpCli: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Do sends the request with the X-Broker-API-Version header. The body, if not nil, is encoded to JSON.
+// Raw body can be sent by passing json.RawMessage.
+func (c *Client) Do(ctx context.Context, method, path string, body interface{}) (*Response, error) {
+	return c.do(ctx, method, path, body, true)
+}
+
+// DoWithoutVersionHeader sends the request without the X-Broker-API-Version header
+func (c *Client) DoWithoutVersionHeader(ctx context.Context, method, path string) (*Response, error) {
+	return c.do(ctx, method, path, nil, false)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, withVersion bool) (*Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, errors.Wrap(err, "while encoding request body")
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+
+	url := strings.TrimSuffix(c.cfg.URL, "/") + path
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating request")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if withVersion {
+		req.Header.Set(APIVersionHeader, APIVersion)
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.httpCli.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while calling %s %s", method, path)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading response of %s %s", method, path)
+	}
+
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}, nil
+}
+
+// Decode decodes the response body into given object
+func (r *Response) Decode(out interface{}) error {
+	if err := json.Unmarshal(r.Body, out); err != nil {
+		return errors.Wrapf(err, "while decoding response body %q", string(r.Body))
+	}
+	return nil
+}
+
+// ExpectStatus returns error when the response status code is not one of the given codes
+func (r *Response) ExpectStatus(codes ...int) error {
+	for _, c := range codes {
+		if r.StatusCode == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected status code %d, expected one of %v, body: %s", r.StatusCode, codes, strings.TrimSpace(string(r.Body)))
+}
@@ -0,0 +1,2 @@
+// Package osb holds the Open Service Broker API types shared by the fake broker and the contract checker.
+package osb
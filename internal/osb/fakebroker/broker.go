@@ -0,0 +1,513 @@
+package fakebroker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Names and IDs of the service and plans offered by the fake broker
+const (
+	ServiceName  = "fake-service"
+	PlanMicro    = "micro"
+	PlanStandard = "standard"
+
+	serviceID      = "1b5a3b2e-4c5e-4b3a-9c57-3f7b1c5e0f01"
+	planMicroID    = "1b5a3b2e-4c5e-4b3a-9c57-3f7b1c5e0f02"
+	planStandardID = "1b5a3b2e-4c5e-4b3a-9c57-3f7b1c5e0f03"
+)
+
+// RejectedParameter is the provision parameter which is always rejected by the broker as invalid,
+// so tests can deterministically trigger the failed provisioning
+const RejectedParameter = "rejected"
+
+// PathPrefix is the path under which the broker is registered by the RegisterRoutes method
+const PathPrefix = "/fake-broker"
+
+// Config holds configuration of the fake broker
+type Config struct {
+	// Latency is added to each request
+	Latency time.Duration `envconfig:"default=0s"`
+	// LatencyJitter is the maximum random delay added to the Latency
+	LatencyJitter time.Duration `envconfig:"default=0s"`
+	// FailureRate is the probability (from 0 to 1) that the provision, update, deprovision, bind
+	// or unbind request fails with the internal server error
+	FailureRate float64 `envconfig:"default=0"`
+	// AsyncOperations defines if the provision, update and deprovision operations are executed asynchronously
+	AsyncOperations bool `envconfig:"default=false"`
+	// OperationDuration defines how long the asynchronous operation stays in progress
+	OperationDuration time.Duration `envconfig:"default=5s"`
+	// OperationFailureRate is the probability (from 0 to 1) that the asynchronous operation fails
+	OperationFailureRate float64 `envconfig:"default=0"`
+	// CredentialsHost and CredentialsPort are returned in the binding credentials
+	CredentialsHost string `envconfig:"default=localhost"`
+	CredentialsPort int    `envconfig:"default=8080"`
+}
+
+// EnvVars returns the configuration as environment variables with given prefix,
+// so the broker started as a separate process reads the same configuration.
+func (c Config) EnvVars(prefix string) map[string]string {
+	return map[string]string{
+		prefix + "_LATENCY":                c.Latency.String(),
+		prefix + "_LATENCY_JITTER":         c.LatencyJitter.String(),
+		prefix + "_FAILURE_RATE":           strconv.FormatFloat(c.FailureRate, 'f', -1, 64),
+		prefix + "_ASYNC_OPERATIONS":       strconv.FormatBool(c.AsyncOperations),
+		prefix + "_OPERATION_DURATION":     c.OperationDuration.String(),
+		prefix + "_OPERATION_FAILURE_RATE": strconv.FormatFloat(c.OperationFailureRate, 'f', -1, 64),
+		prefix + "_CREDENTIALS_HOST":       c.CredentialsHost,
+		prefix + "_CREDENTIALS_PORT":       strconv.Itoa(c.CredentialsPort),
+	}
+}
+
+// Broker is the in-memory Open Service Broker API server with configurable latency and failure injection.
+// It offers one bindable service with two plans and keeps instances and bindings only in memory.
+type Broker struct {
+	cfg Config
+	log logrus.FieldLogger
+
+	mu        sync.Mutex
+	rand      *rand.Rand
+	instances map[string]*instance
+	bindings  map[string]*binding
+}
+
+type instance struct {
+	serviceID  string
+	planID     string
+	parameters map[string]interface{}
+	op         *operation
+}
+
+// operation is the asynchronous operation executed on the instance
+type operation struct {
+	id       string
+	kind     string
+	finishAt time.Time
+	failed   bool
+	// previousPlanID is restored when the update operation fails
+	previousPlanID string
+}
+
+type binding struct {
+	instanceID  string
+	serviceID   string
+	planID      string
+	parameters  map[string]interface{}
+	credentials map[string]interface{}
+}
+
+const (
+	opProvision   = "provision"
+	opUpdate      = "update"
+	opDeprovision = "deprovision"
+)
+
+// New returns new instance of the Broker
+func New(cfg Config, log logrus.FieldLogger) *Broker {
+	return &Broker{
+		cfg:       cfg,
+		log:       log.WithField("service", "fake-broker"),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		instances: map[string]*instance{},
+		bindings:  map[string]*binding{},
+	}
+}
+
+// RegisterRoutes registers the broker endpoints under the PathPrefix in given mux
+func (b *Broker) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle(PathPrefix+"/", http.StripPrefix(PathPrefix, b))
+}
+
+// ServeHTTP handles the Open Service Broker API requests
+func (b *Broker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b.log.Debugf("%s %s", req.Method, req.URL.String())
+
+	if req.Header.Get(osb.APIVersionHeader) == "" {
+		b.writeError(w, http.StatusPreconditionFailed, "", fmt.Sprintf("Missing %s header", osb.APIVersionHeader))
+		return
+	}
+
+	b.delay()
+
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(segments) == 2 && segments[0] == "v2" && segments[1] == "catalog" && req.Method == http.MethodGet:
+		b.catalog(w)
+	case len(segments) == 3 && segments[0] == "v2" && segments[1] == "service_instances":
+		b.serveInstance(w, req, segments[2])
+	case len(segments) == 4 && segments[0] == "v2" && segments[1] == "service_instances" && segments[3] == "last_operation" && req.Method == http.MethodGet:
+		b.lastOperation(w, req, segments[2])
+	case len(segments) == 5 && segments[0] == "v2" && segments[1] == "service_instances" && segments[3] == "service_bindings":
+		b.serveBinding(w, req, segments[2], segments[4])
+	default:
+		b.writeError(w, http.StatusNotFound, "", fmt.Sprintf("%s %s not found", req.Method, req.URL.Path))
+	}
+}
+
+func (b *Broker) serveInstance(w http.ResponseWriter, req *http.Request, id string) {
+	if req.Method != http.MethodPut && req.Method != http.MethodPatch && req.Method != http.MethodDelete {
+		b.writeError(w, http.StatusMethodNotAllowed, "", fmt.Sprintf("Method %s is not allowed", req.Method))
+		return
+	}
+	if b.injectFailure(w) {
+		return
+	}
+	if b.cfg.AsyncOperations && req.URL.Query().Get("accepts_incomplete") != "true" {
+		b.writeError(w, http.StatusUnprocessableEntity, osb.ErrorAsyncRequired, "This service plan requires client support for asynchronous service operations.")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		b.provision(w, req, id)
+	case http.MethodPatch:
+		b.update(w, req, id)
+	case http.MethodDelete:
+		b.deprovision(w, req, id)
+	}
+}
+
+func (b *Broker) serveBinding(w http.ResponseWriter, req *http.Request, instanceID, bindingID string) {
+	if req.Method != http.MethodPut && req.Method != http.MethodDelete {
+		b.writeError(w, http.StatusMethodNotAllowed, "", fmt.Sprintf("Method %s is not allowed", req.Method))
+		return
+	}
+	if b.injectFailure(w) {
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		b.bind(w, req, instanceID, bindingID)
+	case http.MethodDelete:
+		b.unbind(w, req, instanceID, bindingID)
+	}
+}
+
+func (b *Broker) catalog(w http.ResponseWriter) {
+	free := true
+	b.writeJSON(w, http.StatusOK, osb.CatalogResponse{
+		Services: []osb.Service{
+			{
+				Name:          ServiceName,
+				ID:            serviceID,
+				Description:   "Fake service used to test Service Catalog",
+				Tags:          []string{"fake", "service-catalog-tester"},
+				Bindable:      true,
+				PlanUpdatable: true,
+				Plans: []osb.Plan{
+					{ID: planMicroID, Name: PlanMicro, Description: "Micro plan", Free: &free},
+					{ID: planStandardID, Name: PlanStandard, Description: "Standard plan", Free: &free},
+				},
+			},
+		},
+	})
+}
+
+func (b *Broker) provision(w http.ResponseWriter, req *http.Request, id string) {
+	var body osb.ProvisionRequest
+	if !b.decode(w, req, &body) {
+		return
+	}
+	if body.ServiceID != serviceID || !validPlan(body.PlanID) {
+		b.writeError(w, http.StatusBadRequest, "", fmt.Sprintf("Unknown service %q or plan %q", body.ServiceID, body.PlanID))
+		return
+	}
+	if _, found := body.Parameters[RejectedParameter]; found {
+		b.writeError(w, http.StatusBadRequest, "", fmt.Sprintf("Parameter %q is not supported", RejectedParameter))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if existing, found := b.instances[id]; found {
+		if existing.serviceID != body.ServiceID || existing.planID != body.PlanID || !reflect.DeepEqual(existing.parameters, body.Parameters) {
+			b.writeError(w, http.StatusConflict, "", fmt.Sprintf("Instance %s already exists with different attributes", id))
+			return
+		}
+		if existing.op != nil && existing.op.kind == opProvision {
+			b.writeJSON(w, http.StatusAccepted, osb.ProvisionResponse{Operation: existing.op.id})
+			return
+		}
+		b.writeJSON(w, http.StatusOK, osb.ProvisionResponse{})
+		return
+	}
+
+	si := &instance{serviceID: body.ServiceID, planID: body.PlanID, parameters: body.Parameters}
+	b.instances[id] = si
+
+	if !b.cfg.AsyncOperations {
+		b.writeJSON(w, http.StatusCreated, osb.ProvisionResponse{})
+		return
+	}
+
+	si.op = b.newOperation(opProvision)
+	b.writeJSON(w, http.StatusAccepted, osb.ProvisionResponse{Operation: si.op.id})
+}
+
+func (b *Broker) update(w http.ResponseWriter, req *http.Request, id string) {
+	var body osb.UpdateRequest
+	if !b.decode(w, req, &body) {
+		return
+	}
+	if body.PlanID != "" && !validPlan(body.PlanID) {
+		b.writeError(w, http.StatusBadRequest, "", fmt.Sprintf("Unknown plan %q", body.PlanID))
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	si, found := b.instances[id]
+	if !found {
+		b.writeError(w, http.StatusNotFound, "", fmt.Sprintf("Instance %s not found", id))
+		return
+	}
+	if si.op != nil {
+		b.writeError(w, http.StatusUnprocessableEntity, osb.ErrorConcurrencyError, fmt.Sprintf("Operation %s is in progress", si.op.kind))
+		return
+	}
+
+	previousPlanID := si.planID
+	if body.PlanID != "" {
+		si.planID = body.PlanID
+	}
+	if body.Parameters != nil {
+		si.parameters = body.Parameters
+	}
+
+	if !b.cfg.AsyncOperations {
+		b.writeJSON(w, http.StatusOK, osb.OperationResponse{})
+		return
+	}
+
+	si.op = b.newOperation(opUpdate)
+	si.op.previousPlanID = previousPlanID
+	b.writeJSON(w, http.StatusAccepted, osb.OperationResponse{Operation: si.op.id})
+}
+
+func (b *Broker) deprovision(w http.ResponseWriter, req *http.Request, id string) {
+	if !b.requireQueryIDs(w, req) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	si, found := b.instances[id]
+	if !found {
+		b.writeJSON(w, http.StatusGone, struct{}{})
+		return
+	}
+	if si.op != nil && si.op.kind == opDeprovision {
+		b.writeJSON(w, http.StatusAccepted, osb.OperationResponse{Operation: si.op.id})
+		return
+	}
+	if si.op != nil {
+		b.writeError(w, http.StatusUnprocessableEntity, osb.ErrorConcurrencyError, fmt.Sprintf("Operation %s is in progress", si.op.kind))
+		return
+	}
+
+	if !b.cfg.AsyncOperations {
+		b.removeInstance(id)
+		b.writeJSON(w, http.StatusOK, struct{}{})
+		return
+	}
+
+	si.op = b.newOperation(opDeprovision)
+	b.writeJSON(w, http.StatusAccepted, osb.OperationResponse{Operation: si.op.id})
+}
+
+func (b *Broker) lastOperation(w http.ResponseWriter, req *http.Request, id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	si, found := b.instances[id]
+	if !found {
+		b.writeJSON(w, http.StatusGone, struct{}{})
+		return
+	}
+	if si.op == nil {
+		b.writeJSON(w, http.StatusOK, osb.LastOperationResponse{State: osb.StateSucceeded})
+		return
+	}
+	if opID := req.URL.Query().Get("operation"); opID != "" && opID != si.op.id {
+		b.writeError(w, http.StatusBadRequest, "", fmt.Sprintf("Unknown operation %q", opID))
+		return
+	}
+
+	op := si.op
+	if time.Now().Before(op.finishAt) {
+		b.writeJSON(w, http.StatusOK, osb.LastOperationResponse{
+			State:       osb.StateInProgress,
+			Description: fmt.Sprintf("Operation %s in progress", op.kind),
+		})
+		return
+	}
+
+	si.op = nil
+	if op.failed {
+		switch op.kind {
+		case opProvision:
+			delete(b.instances, id)
+		case opUpdate:
+			si.planID = op.previousPlanID
+		}
+		b.writeJSON(w, http.StatusOK, osb.LastOperationResponse{
+			State:       osb.StateFailed,
+			Description: fmt.Sprintf("Injected failure of operation %s", op.kind),
+		})
+		return
+	}
+
+	if op.kind == opDeprovision {
+		b.removeInstance(id)
+		b.writeJSON(w, http.StatusGone, struct{}{})
+		return
+	}
+	b.writeJSON(w, http.StatusOK, osb.LastOperationResponse{State: osb.StateSucceeded})
+}
+
+func (b *Broker) bind(w http.ResponseWriter, req *http.Request, instanceID, bindingID string) {
+	var body osb.BindRequest
+	if !b.decode(w, req, &body) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	si, found := b.instances[instanceID]
+	if !found || (si.op != nil && si.op.kind == opProvision) {
+		b.writeError(w, http.StatusUnprocessableEntity, "", fmt.Sprintf("Instance %s does not exist or is not provisioned yet", instanceID))
+		return
+	}
+
+	if existing, found := b.bindings[bindingID]; found {
+		if existing.instanceID != instanceID || existing.serviceID != body.ServiceID || existing.planID != body.PlanID ||
+			!reflect.DeepEqual(existing.parameters, body.Parameters) {
+			b.writeError(w, http.StatusConflict, "", fmt.Sprintf("Binding %s already exists with different attributes", bindingID))
+			return
+		}
+		b.writeJSON(w, http.StatusOK, osb.BindResponse{Credentials: existing.credentials})
+		return
+	}
+
+	sb := &binding{
+		instanceID: instanceID,
+		serviceID:  body.ServiceID,
+		planID:     body.PlanID,
+		parameters: body.Parameters,
+		credentials: map[string]interface{}{
+			"host":     b.cfg.CredentialsHost,
+			"port":     strconv.Itoa(b.cfg.CredentialsPort),
+			"username": "fake",
+			"password": uuid.NewV4().String(),
+		},
+	}
+	b.bindings[bindingID] = sb
+	b.writeJSON(w, http.StatusCreated, osb.BindResponse{Credentials: sb.credentials})
+}
+
+func (b *Broker) unbind(w http.ResponseWriter, req *http.Request, instanceID, bindingID string) {
+	if !b.requireQueryIDs(w, req) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sb, found := b.bindings[bindingID]
+	if !found || sb.instanceID != instanceID {
+		b.writeJSON(w, http.StatusGone, struct{}{})
+		return
+	}
+
+	delete(b.bindings, bindingID)
+	b.writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// removeInstance removes the instance together with its bindings. Must be called with the lock held.
+func (b *Broker) removeInstance(id string) {
+	delete(b.instances, id)
+	for bindingID, sb := range b.bindings {
+		if sb.instanceID == id {
+			delete(b.bindings, bindingID)
+		}
+	}
+}
+
+// newOperation creates asynchronous operation. Must be called with the lock held.
+func (b *Broker) newOperation(kind string) *operation {
+	return &operation{
+		id:       uuid.NewV4().String(),
+		kind:     kind,
+		finishAt: time.Now().Add(b.cfg.OperationDuration),
+		failed:   b.rand.Float64() < b.cfg.OperationFailureRate,
+	}
+}
+
+func (b *Broker) delay() {
+	latency := b.cfg.Latency
+	if b.cfg.LatencyJitter > 0 {
+		b.mu.Lock()
+		latency += time.Duration(b.rand.Int63n(int64(b.cfg.LatencyJitter)))
+		b.mu.Unlock()
+	}
+	time.Sleep(latency)
+}
+
+func (b *Broker) injectFailure(w http.ResponseWriter) bool {
+	b.mu.Lock()
+	fail := b.rand.Float64() < b.cfg.FailureRate
+	b.mu.Unlock()
+
+	if fail {
+		b.writeError(w, http.StatusInternalServerError, "", "Injected failure")
+	}
+	return fail
+}
+
+func (b *Broker) requireQueryIDs(w http.ResponseWriter, req *http.Request) bool {
+	q := req.URL.Query()
+	if q.Get("service_id") == "" || q.Get("plan_id") == "" {
+		b.writeError(w, http.StatusBadRequest, "", "Query parameters service_id and plan_id are required")
+		return false
+	}
+	return true
+}
+
+func (b *Broker) decode(w http.ResponseWriter, req *http.Request, body interface{}) bool {
+	if err := json.NewDecoder(req.Body).Decode(body); err != nil {
+		b.writeError(w, http.StatusBadRequest, "", fmt.Sprintf("Cannot decode request body: %v", err))
+		return false
+	}
+	return true
+}
+
+func (b *Broker) writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		b.log.Errorf("Got error while encoding response: %v", err)
+	}
+}
+
+func (b *Broker) writeError(w http.ResponseWriter, code int, errCode, description string) {
+	b.writeJSON(w, code, osb.ErrorResponse{Error: errCode, Description: description})
+}
+
+func validPlan(id string) bool {
+	return id == planMicroID || id == planStandardID
+}
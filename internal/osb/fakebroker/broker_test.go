@@ -0,0 +1,208 @@
+package fakebroker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	instancePath   = "/v2/service_instances/instance-1"
+	bindingPath    = instancePath + "/service_bindings/binding-1"
+	lastOpPath     = instancePath + "/last_operation"
+	asyncQuery     = "?accepts_incomplete=true"
+	deleteQuery    = "?service_id=" + serviceID + "&plan_id=" + planMicroID
+	asyncDelQuery  = deleteQuery + "&accepts_incomplete=true"
+	missingPath    = "/v2/service_instances/missing"
+	provisionMicro = `{"service_id": "` + serviceID + `", "plan_id": "` + planMicroID + `"}`
+)
+
+// call is a single request sent to the broker together with the expected response
+type call struct {
+	method string
+	path   string
+	body   string
+	// wait is the time to wait before the request is sent
+	wait time.Duration
+	// withoutAPIVersion skips the API version header
+	withoutAPIVersion bool
+
+	expCode int
+	// expBody is the part of the response body which must be present
+	expBody string
+}
+
+func TestBroker(t *testing.T) {
+	tests := map[string]struct {
+		cfg   Config
+		calls []call
+	}{
+		"catalog": {
+			calls: []call{
+				{method: http.MethodGet, path: "/v2/catalog", expCode: http.StatusOK, expBody: `"name":"` + ServiceName + `"`},
+				{method: http.MethodGet, path: "/v2/catalog", expCode: http.StatusOK, expBody: `"name":"` + PlanStandard + `"`},
+				{method: http.MethodGet, path: "/v2/catalog", withoutAPIVersion: true, expCode: http.StatusPreconditionFailed},
+			},
+		},
+		"synchronous provision is idempotent": {
+			calls: []call{
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusCreated},
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusOK},
+				{method: http.MethodPut, path: instancePath, body: `{"service_id": "` + serviceID + `", "plan_id": "` + planStandardID + `"}`, expCode: http.StatusConflict},
+				{method: http.MethodGet, path: lastOpPath, expCode: http.StatusOK, expBody: string(osb.StateSucceeded)},
+				{method: http.MethodDelete, path: instancePath + deleteQuery, expCode: http.StatusOK},
+				{method: http.MethodDelete, path: instancePath + deleteQuery, expCode: http.StatusGone},
+			},
+		},
+		"asynchronous provision is polled": {
+			cfg: Config{AsyncOperations: true, OperationDuration: 200 * time.Millisecond},
+			calls: []call{
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusUnprocessableEntity, expBody: osb.ErrorAsyncRequired},
+				{method: http.MethodPut, path: instancePath + asyncQuery, body: provisionMicro, expCode: http.StatusAccepted, expBody: `"operation"`},
+				{method: http.MethodPut, path: instancePath + asyncQuery, body: provisionMicro, expCode: http.StatusAccepted, expBody: `"operation"`},
+				{method: http.MethodGet, path: lastOpPath, expCode: http.StatusOK, expBody: string(osb.StateInProgress)},
+				{method: http.MethodGet, path: lastOpPath, wait: 250 * time.Millisecond, expCode: http.StatusOK, expBody: string(osb.StateSucceeded)},
+				{method: http.MethodPut, path: instancePath + asyncQuery, body: provisionMicro, expCode: http.StatusOK},
+				{method: http.MethodDelete, path: instancePath + asyncDelQuery, expCode: http.StatusAccepted},
+				{method: http.MethodGet, path: lastOpPath, wait: 250 * time.Millisecond, expCode: http.StatusGone},
+			},
+		},
+		"update to the standard plan": {
+			calls: []call{
+				{method: http.MethodPatch, path: instancePath, body: `{"service_id": "` + serviceID + `", "plan_id": "` + planStandardID + `"}`, expCode: http.StatusNotFound},
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusCreated},
+				{method: http.MethodPatch, path: instancePath, body: `{"service_id": "` + serviceID + `", "plan_id": "unknown"}`, expCode: http.StatusBadRequest},
+				{method: http.MethodPatch, path: instancePath, body: `{"service_id": "` + serviceID + `", "plan_id": "` + planStandardID + `"}`, expCode: http.StatusOK},
+				// the instance has the standard plan now, so the provision with the micro plan conflicts
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusConflict},
+			},
+		},
+		"bind and unbind": {
+			cfg: Config{CredentialsHost: "redis.example.com", CredentialsPort: 6379},
+			calls: []call{
+				{method: http.MethodPut, path: bindingPath, body: provisionMicro, expCode: http.StatusUnprocessableEntity},
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusCreated},
+				{method: http.MethodPut, path: bindingPath, body: provisionMicro, expCode: http.StatusCreated, expBody: `"host":"redis.example.com","password":`},
+				{method: http.MethodPut, path: bindingPath, body: provisionMicro, expCode: http.StatusOK, expBody: `"port":"6379"`},
+				{method: http.MethodPut, path: bindingPath, body: `{"service_id": "` + serviceID + `", "plan_id": "` + planMicroID + `", "parameters": {"a": 1}}`, expCode: http.StatusConflict},
+				{method: http.MethodDelete, path: bindingPath, expCode: http.StatusBadRequest},
+				{method: http.MethodDelete, path: bindingPath + deleteQuery, expCode: http.StatusOK},
+				{method: http.MethodDelete, path: bindingPath + deleteQuery, expCode: http.StatusGone},
+			},
+		},
+		"rejected parameter": {
+			calls: []call{
+				{method: http.MethodPut, path: instancePath, body: `{"service_id": "` + serviceID + `", "plan_id": "` + planMicroID + `", "parameters": {"` + RejectedParameter + `": true}}`, expCode: http.StatusBadRequest, expBody: RejectedParameter},
+				{method: http.MethodGet, path: lastOpPath, expCode: http.StatusGone},
+			},
+		},
+		"unknown plan": {
+			calls: []call{
+				{method: http.MethodPut, path: instancePath, body: `{"service_id": "` + serviceID + `", "plan_id": "unknown"}`, expCode: http.StatusBadRequest},
+			},
+		},
+		"injected request failures": {
+			cfg: Config{FailureRate: 1},
+			calls: []call{
+				{method: http.MethodGet, path: "/v2/catalog", expCode: http.StatusOK},
+				{method: http.MethodPut, path: instancePath, body: provisionMicro, expCode: http.StatusInternalServerError},
+				{method: http.MethodPut, path: bindingPath, body: provisionMicro, expCode: http.StatusInternalServerError},
+				{method: http.MethodDelete, path: missingPath + deleteQuery, expCode: http.StatusInternalServerError},
+			},
+		},
+		"injected operation failures": {
+			cfg: Config{AsyncOperations: true, OperationFailureRate: 1},
+			calls: []call{
+				{method: http.MethodPut, path: instancePath + asyncQuery, body: provisionMicro, expCode: http.StatusAccepted},
+				{method: http.MethodGet, path: lastOpPath, expCode: http.StatusOK, expBody: string(osb.StateFailed)},
+				// the instance which failed to provision is removed
+				{method: http.MethodGet, path: lastOpPath, expCode: http.StatusGone},
+			},
+		},
+		"unknown path": {
+			calls: []call{
+				{method: http.MethodGet, path: "/v2/unknown", expCode: http.StatusNotFound},
+				{method: http.MethodPost, path: instancePath, body: provisionMicro, expCode: http.StatusMethodNotAllowed},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := logrus.New()
+			log.Out = ioutil.Discard
+			srv := httptest.NewServer(New(tc.cfg, log))
+			defer srv.Close()
+
+			for i, c := range tc.calls {
+				time.Sleep(c.wait)
+
+				code, body := send(t, srv.URL, c)
+				if code != c.expCode {
+					t.Fatalf("call %d %s %s: got status code %d, expected %d, body: %s", i, c.method, c.path, code, c.expCode, body)
+				}
+				if !strings.Contains(body, c.expBody) {
+					t.Fatalf("call %d %s %s: got body %s, expected it to contain %s", i, c.method, c.path, body, c.expBody)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigEnvVars(t *testing.T) {
+	cfg := Config{Latency: time.Second, FailureRate: 0.5, AsyncOperations: true, CredentialsPort: 6379}
+
+	envs := cfg.EnvVars("APP_BROKER")
+
+	exp := map[string]string{
+		"APP_BROKER_LATENCY":          "1s",
+		"APP_BROKER_FAILURE_RATE":     "0.5",
+		"APP_BROKER_ASYNC_OPERATIONS": "true",
+		"APP_BROKER_CREDENTIALS_PORT": "6379",
+	}
+	for name, value := range exp {
+		if envs[name] != value {
+			t.Errorf("got %s=%q, expected %q", name, envs[name], value)
+		}
+	}
+}
+
+func send(t *testing.T, url string, c call) (int, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	if c.body != "" {
+		if !json.Valid([]byte(c.body)) {
+			t.Fatalf("invalid request body %s", c.body)
+		}
+		body.WriteString(c.body)
+	}
+
+	req, err := http.NewRequest(c.method, url+c.path, &body)
+	if err != nil {
+		t.Fatalf("cannot create request: %v", err)
+	}
+	if !c.withoutAPIVersion {
+		req.Header.Set(osb.APIVersionHeader, "2.13")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cannot send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("cannot read response: %v", err)
+	}
+	return resp.StatusCode, string(raw)
+}
@@ -0,0 +1,106 @@
+package osb
+
+const (
+	// APIVersionHeader is the header which holds the version of the Open Service Broker API used by the platform
+	APIVersionHeader = "X-Broker-API-Version"
+	// APIVersion is the supported version of the Open Service Broker API
+	APIVersion = "2.13"
+)
+
+// Error codes defined by the Open Service Broker API
+const (
+	ErrorAsyncRequired    = "AsyncRequired"
+	ErrorConcurrencyError = "ConcurrencyError"
+)
+
+// CatalogResponse is the response of the catalog endpoint
+type CatalogResponse struct {
+	Services []Service `json:"services"`
+}
+
+// Service is a single service offered by the broker
+type Service struct {
+	Name          string                 `json:"name"`
+	ID            string                 `json:"id"`
+	Description   string                 `json:"description"`
+	Tags          []string               `json:"tags,omitempty"`
+	Bindable      bool                   `json:"bindable"`
+	PlanUpdatable bool                   `json:"plan_updateable,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Plans         []Plan                 `json:"plans"`
+}
+
+// Plan is a single plan of the service
+type Plan struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Free        *bool                  `json:"free,omitempty"`
+	Bindable    *bool                  `json:"bindable,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ProvisionRequest is the body of the provision request
+type ProvisionRequest struct {
+	ServiceID        string                 `json:"service_id"`
+	PlanID           string                 `json:"plan_id"`
+	OrganizationGUID string                 `json:"organization_guid"`
+	SpaceGUID        string                 `json:"space_guid"`
+	Parameters       map[string]interface{} `json:"parameters,omitempty"`
+	Context          map[string]interface{} `json:"context,omitempty"`
+}
+
+// ProvisionResponse is the body of the provision response
+type ProvisionResponse struct {
+	DashboardURL string `json:"dashboard_url,omitempty"`
+	Operation    string `json:"operation,omitempty"`
+}
+
+// UpdateRequest is the body of the update request
+type UpdateRequest struct {
+	ServiceID  string                 `json:"service_id"`
+	PlanID     string                 `json:"plan_id,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Context    map[string]interface{} `json:"context,omitempty"`
+}
+
+// OperationResponse is the body of the update and deprovision responses
+type OperationResponse struct {
+	Operation string `json:"operation,omitempty"`
+}
+
+// LastOperationState is the state of the asynchronous operation
+type LastOperationState string
+
+// States of the asynchronous operation
+const (
+	StateInProgress LastOperationState = "in progress"
+	StateSucceeded  LastOperationState = "succeeded"
+	StateFailed     LastOperationState = "failed"
+)
+
+// LastOperationResponse is the body of the last operation response
+type LastOperationResponse struct {
+	State       LastOperationState `json:"state"`
+	Description string             `json:"description,omitempty"`
+}
+
+// BindRequest is the body of the bind request
+type BindRequest struct {
+	ServiceID    string                 `json:"service_id"`
+	PlanID       string                 `json:"plan_id"`
+	BindResource map[string]interface{} `json:"bind_resource,omitempty"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Context      map[string]interface{} `json:"context,omitempty"`
+}
+
+// BindResponse is the body of the bind response
+type BindResponse struct {
+	Credentials map[string]interface{} `json:"credentials,omitempty"`
+}
+
+// ErrorResponse is the body of the error responses
+type ErrorResponse struct {
+	Error       string `json:"error,omitempty"`
+	Description string `json:"description,omitempty"`
+}
@@ -0,0 +1,2 @@
+// Package cron parses standard 5-field cron expressions and calculates their activation times.
+package cron
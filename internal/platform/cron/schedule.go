@@ -0,0 +1,211 @@
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxSearchYears limits searching for the next activation time of expressions which never match, e.g. `0 0 30 2 *`
+const maxSearchYears = 5
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny and dowAny are needed because when both day fields are restricted,
+	// the schedule activates when either of them matches
+	domAny, dowAny bool
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	// 7 is also accepted as Sunday and it is folded into 0 after parsing
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses the cron expression in the standard format: `minute hour day-of-month month day-of-week`.
+// Each field supports `*`, single values, ranges `a-b`, steps `*/n` and `a-b/n` and comma separated lists.
+// Month and day-of-week fields also accept three-letter English names.
+// Descriptors such as `@hourly`, `@daily`, `@weekly`, `@monthly` and `@yearly` are also supported.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, found := descriptors[strings.ToLower(expr)]; found {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("expected 5 fields in cron expression %q, got %d", expr, len(fields))
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, errors.Wrap(err, "while parsing minute field")
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, errors.Wrap(err, "while parsing hour field")
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, errors.Wrap(err, "while parsing day-of-month field")
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, errors.Wrap(err, "while parsing month field")
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, errors.Wrap(err, "while parsing day-of-week field")
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+
+	return &s, nil
+}
+
+// Next returns the first activation time after given time.
+// Zero time is returned when the schedule cannot be satisfied.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if !s.matches(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matches(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matches(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.matches(s.dom, t.Day())
+	dowMatch := s.matches(s.dow, int(t.Weekday()))
+
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (*Schedule) matches(bits uint64, val int) bool {
+	return bits&(1<<uint(val)) != 0
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parsePart(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func parsePart(part string, f field) (uint64, error) {
+	rangeExpr, step := part, 1
+	if i := strings.Index(part, "/"); i >= 0 {
+		var err error
+		rangeExpr = part[:i]
+		step, err = strconv.Atoi(part[i+1:])
+		if err != nil || step <= 0 {
+			return 0, errors.Errorf("invalid step in %q", part)
+		}
+	}
+
+	var start, end int
+	switch {
+	case rangeExpr == "*":
+		start, end = f.min, f.max
+	case strings.Contains(rangeExpr, "-"):
+		bounds := strings.SplitN(rangeExpr, "-", 2)
+		var err error
+		if start, err = parseValue(bounds[0], f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(bounds[1], f); err != nil {
+			return 0, err
+		}
+	default:
+		val, err := parseValue(rangeExpr, f)
+		if err != nil {
+			return 0, err
+		}
+		start, end = val, val
+		// `a/n` means from `a` to the end of the range
+		if step > 1 {
+			end = f.max
+		}
+	}
+
+	if start > end {
+		return 0, errors.Errorf("invalid range in %q", part)
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(expr string, f field) (int, error) {
+	if v, found := f.names[strings.ToUpper(expr)]; found {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", expr)
+	}
+	if v < f.min || v > f.max {
+		return 0, errors.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
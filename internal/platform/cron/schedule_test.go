@@ -0,0 +1,132 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseField(t *testing.T) {
+	bitsOf := func(values ...int) uint64 {
+		var bits uint64
+		for _, v := range values {
+			bits |= 1 << uint(v)
+		}
+		return bits
+	}
+
+	tests := map[string]struct {
+		expr string
+		f    field
+		exp  uint64
+	}{
+		"any":                  {expr: "*", f: hourField, exp: bitsOf(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23)},
+		"single value":         {expr: "5", f: minuteField, exp: bitsOf(5)},
+		"range":                {expr: "1-4", f: domField, exp: bitsOf(1, 2, 3, 4)},
+		"step over any":        {expr: "*/15", f: minuteField, exp: bitsOf(0, 15, 30, 45)},
+		"step over range":      {expr: "10-20/5", f: minuteField, exp: bitsOf(10, 15, 20)},
+		"step from value":      {expr: "20/10", f: minuteField, exp: bitsOf(20, 30, 40, 50)},
+		"list":                 {expr: "1,3,5-6", f: dowField, exp: bitsOf(1, 3, 5, 6)},
+		"month names":          {expr: "JAN,mar-May", f: monthField, exp: bitsOf(1, 3, 4, 5)},
+		"day-of-week names":    {expr: "MON-FRI", f: dowField, exp: bitsOf(1, 2, 3, 4, 5)},
+		"range to the maximum": {expr: "5-7", f: dowField, exp: bitsOf(5, 6, 7)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseField(tc.expr, tc.f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.exp {
+				t.Errorf("got bits %b, expected %b", got, tc.exp)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"too few fields":        "* * * *",
+		"too many fields":       "* * * * * *",
+		"minute out of range":   "60 * * * *",
+		"hour out of range":     "* 24 * * *",
+		"day-of-month zero":     "* * 0 * *",
+		"month out of range":    "* * * 13 *",
+		"day-of-week too big":   "* * * * 8",
+		"unknown name":          "* * * FOO *",
+		"not a number":          "a * * * *",
+		"inverted range":        "* 10-5 * * *",
+		"zero step":             "*/0 * * * *",
+		"negative step":         "*/-1 * * * *",
+		"empty list element":    "1,,2 * * * *",
+		"unknown descriptor":    "@every5m",
+		"empty expression":      "",
+		"missing range end":     "1- * * * *",
+		"step without a number": "*/ * * * *",
+	}
+
+	for name, expr := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(expr); err == nil {
+				t.Errorf("expected error for %q", expr)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatalf("cannot parse time %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	tests := map[string]struct {
+		expr  string
+		after string
+		exp   string
+	}{
+		"every minute skips the current minute": {expr: "* * * * *", after: "2026-10-19 10:07", exp: "2026-10-19 10:08"},
+		"step within the hour":                  {expr: "*/15 * * * *", after: "2026-10-19 10:07", exp: "2026-10-19 10:15"},
+		"next hour":                             {expr: "5 * * * *", after: "2026-10-19 10:07", exp: "2026-10-19 11:05"},
+		"next day":                              {expr: "0 3 * * *", after: "2026-10-19 10:07", exp: "2026-10-20 03:00"},
+		"month boundary":                        {expr: "0 0 1 * *", after: "2026-01-31 12:00", exp: "2026-02-01 00:00"},
+		"year boundary":                         {expr: "0 0 1 1 *", after: "2026-12-15 00:00", exp: "2027-01-01 00:00"},
+		"day missing in the month":              {expr: "0 0 31 * *", after: "2026-04-01 00:00", exp: "2026-05-31 00:00"},
+		"leap day":                              {expr: "0 0 29 2 *", after: "2026-03-01 00:00", exp: "2028-02-29 00:00"},
+		"weekdays over the weekend":             {expr: "30 2 * * MON-FRI", after: "2026-10-24 10:00", exp: "2026-10-26 02:30"},
+		"sunday as 7":                           {expr: "0 0 * * 7", after: "2026-10-19 10:00", exp: "2026-10-25 00:00"},
+		"sunday as 0":                           {expr: "0 0 * * 0", after: "2026-10-19 10:00", exp: "2026-10-25 00:00"},
+		"day-of-week across month boundary":     {expr: "0 0 * * SUN", after: "2026-02-28 10:00", exp: "2026-03-01 00:00"},
+		"day-of-month or day-of-week":           {expr: "0 12 13 * FRI", after: "2026-02-01 00:00", exp: "2026-02-06 12:00"},
+		"only day-of-month restricted":          {expr: "0 12 13 * *", after: "2026-02-01 00:00", exp: "2026-02-13 12:00"},
+		"descriptor":                            {expr: "@monthly", after: "2026-10-19 10:00", exp: "2026-11-01 00:00"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := s.Next(at(tc.after))
+			if exp := at(tc.exp); !got.Equal(exp) {
+				t.Errorf("got %v, expected %v", got, exp)
+			}
+		})
+	}
+}
+
+func TestScheduleNextNeverMatches(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := s.Next(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("expected zero time, got %v", got)
+	}
+}
@@ -0,0 +1,2 @@
+// Package labels holds labels set by the tester on created resources, shared by tests and by components which clean them up.
+package labels
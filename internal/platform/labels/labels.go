@@ -0,0 +1,20 @@
+package labels
+
+// Labels set on each namespace created by tests. They allow to find and clean up namespaces
+// which were not deleted, e.g. because the tester was killed during the test execution.
+const (
+	// TestNamespace marks namespaces created by tests
+	TestNamespace = "service-catalog-tester.kyma-project.io/test-namespace"
+	// RunID holds the ID of the test execution which created the namespace
+	RunID = "service-catalog-tester.kyma-project.io/run-id"
+	// CreatedAt holds the namespace creation time as Unix timestamp
+	CreatedAt = "service-catalog-tester.kyma-project.io/created-at"
+)
+
+// SoakNamespace marks the namespace with the soak test pool. The namespace is not a test namespace,
+// so it is not removed by the namespace janitor.
+const SoakNamespace = "service-catalog-tester.kyma-project.io/soak-namespace"
+
+// UsageKind marks cluster-wide UsageKinds created by tests. They are labeled also with the RunID and CreatedAt labels,
+// so the janitor removes UsageKinds which were not deleted, like leaked namespaces.
+const UsageKind = "service-catalog-tester.kyma-project.io/usage-kind"
@@ -0,0 +1,2 @@
+// Package stats calculates latency statistics of the tested operations.
+package stats
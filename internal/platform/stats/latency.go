@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Latencies collects measured durations. It is safe for concurrent use.
+type Latencies struct {
+	mu     sync.Mutex
+	values []time.Duration
+}
+
+// Summary describes the distribution of the collected durations
+type Summary struct {
+	Count int           `json:"count"`
+	Min   time.Duration `json:"min"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// Add records given duration
+func (l *Latencies) Add(d time.Duration) {
+	l.mu.Lock()
+	l.values = append(l.values, d)
+	l.mu.Unlock()
+}
+
+// Summary calculates the distribution of the recorded durations
+func (l *Latencies) Summary() Summary {
+	l.mu.Lock()
+	sorted := make([]time.Duration, len(l.values))
+	copy(sorted, l.values)
+	l.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return Summary{}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return Summary{
+		Count: len(sorted),
+		Min:   sorted[0],
+		P50:   Percentile(sorted, 50),
+		P90:   Percentile(sorted, 90),
+		P99:   Percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// Percentile returns the p-th percentile of the sorted durations using the nearest-rank method
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// String returns the summary in a human readable format
+func (s Summary) String() string {
+	return fmt.Sprintf("count: %d, min: %v, p50: %v, p90: %v, p99: %v, max: %v",
+		s.Count, round(s.Min), round(s.P50), round(s.P90), round(s.P99), round(s.Max))
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond / 10)
+}
@@ -0,0 +1,47 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// httpProbe checks that the service responds to the HTTP GET request without the client or server error
+type httpProbe struct {
+	cfg Config
+}
+
+func (p *httpProbe) Check(ctx context.Context, creds Credentials) error {
+	url := creds[p.cfg.URLKey]
+	if url == "" {
+		addr, err := endpoint(p.cfg, creds)
+		if err != nil {
+			return err
+		}
+		url = "http://" + addr
+	}
+	url = strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(p.cfg.HTTPPath, "/")
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "while creating request")
+	}
+
+	cli := &http.Client{Timeout: p.cfg.Timeout}
+	resp, err := cli.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrapf(err, "while calling %s", url)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("GET %s returned unexpected status code %d", url, resp.StatusCode)
+	}
+	return nil
+}
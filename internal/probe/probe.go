@@ -0,0 +1,75 @@
+// Package probe provides checks which use the ServiceBinding credentials to talk to the provisioned service.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Kind is the kind of the service probe
+type Kind string
+
+// Supported kinds of the service probe
+const (
+	KindNone  Kind = "none"
+	KindTCP   Kind = "tcp"
+	KindHTTP  Kind = "http"
+	KindRedis Kind = "redis"
+)
+
+// Unmarshal parses and validates the Kind from the environment variable
+func (k *Kind) Unmarshal(s string) error {
+	switch kind := Kind(s); kind {
+	case KindNone, KindTCP, KindHTTP, KindRedis:
+		*k = kind
+		return nil
+	default:
+		return fmt.Errorf("unknown service probe kind %q, expected one of: %s, %s, %s, %s", s, KindNone, KindTCP, KindHTTP, KindRedis)
+	}
+}
+
+// Config holds configuration of the service probe. Keys define under which ServiceBinding Secret keys
+// the probe looks for the service endpoint and credentials.
+type Config struct {
+	Kind        Kind   `envconfig:"default=redis"`
+	HostKey     string `envconfig:"default=HOST"`
+	PortKey     string `envconfig:"default=PORT"`
+	PasswordKey string `envconfig:"default=REDIS_PASSWORD"`
+	// URLKey is used by the HTTP probe. When the Secret does not contain it, the URL is built from the host and port.
+	URLKey   string        `envconfig:"default=URL"`
+	HTTPPath string        `envconfig:"default=/"`
+	Timeout  time.Duration `envconfig:"default=10s"`
+}
+
+// Credentials holds the content of the ServiceBinding Secret
+type Credentials map[string]string
+
+// Probe checks that the service described by the credentials is usable
+type Probe interface {
+	Check(ctx context.Context, creds Credentials) error
+}
+
+// New returns the probe of the configured kind or nil for the KindNone
+func New(cfg Config) Probe {
+	switch cfg.Kind {
+	case KindTCP:
+		return &tcpProbe{cfg: cfg}
+	case KindHTTP:
+		return &httpProbe{cfg: cfg}
+	case KindRedis:
+		return &redisProbe{cfg: cfg}
+	default:
+		return nil
+	}
+}
+
+// endpoint returns the host:port address from the credentials
+func endpoint(cfg Config, creds Credentials) (string, error) {
+	host, port := creds[cfg.HostKey], creds[cfg.PortKey]
+	if host == "" || port == "" {
+		return "", fmt.Errorf("credentials do not contain the endpoint under the %q and %q keys", cfg.HostKey, cfg.PortKey)
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,132 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// redisProbe checks that the Redis accepts the credentials and stores data.
+// It executes PING, SET, GET and DEL commands using the RESP protocol.
+type redisProbe struct {
+	cfg Config
+}
+
+func (p *redisProbe) Check(ctx context.Context, creds Credentials) error {
+	addr, err := endpoint(p.cfg, creds)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: p.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "while connecting to %s", addr)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(p.cfg.Timeout)); err != nil {
+		return err
+	}
+
+	return p.check(conn, creds)
+}
+
+// check executes the probe commands over the established connection
+func (p *redisProbe) check(conn io.ReadWriter, creds Credentials) error {
+	cli := &respConn{rw: conn, r: bufio.NewReader(conn)}
+
+	if password := creds[p.cfg.PasswordKey]; password != "" {
+		if _, err := cli.do("AUTH", password); err != nil {
+			return errors.Wrap(err, "while authenticating")
+		}
+	}
+
+	pong, err := cli.do("PING")
+	if err != nil {
+		return errors.Wrap(err, "while executing PING")
+	}
+	if pong != "PONG" {
+		return fmt.Errorf("PING returned %q, expected PONG", pong)
+	}
+
+	key := fmt.Sprintf("service-catalog-tester:probe:%d", time.Now().UnixNano())
+	value := strconv.FormatInt(time.Now().Unix(), 10)
+	if _, err := cli.do("SET", key, value, "EX", "60"); err != nil {
+		return errors.Wrap(err, "while executing SET")
+	}
+	got, err := cli.do("GET", key)
+	if err != nil {
+		return errors.Wrap(err, "while executing GET")
+	}
+	if got != value {
+		return fmt.Errorf("GET returned %q, expected %q", got, value)
+	}
+	if _, err := cli.do("DEL", key); err != nil {
+		return errors.Wrap(err, "while executing DEL")
+	}
+
+	return nil
+}
+
+// respConn executes Redis commands which return simple strings, integers or bulk strings
+type respConn struct {
+	rw io.Writer
+	r  *bufio.Reader
+}
+
+func (c *respConn) do(args ...string) (string, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.rw.Write(buf.Bytes()); err != nil {
+		return "", err
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("redis error: %s", line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", errors.Wrapf(err, "while parsing bulk string length %q", line)
+		}
+		if size < 0 {
+			return "", errors.New("nil reply")
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return "", err
+		}
+		return string(data[:size]), nil
+	default:
+		return "", fmt.Errorf("unsupported reply %q", line)
+	}
+}
+
+func (c *respConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
@@ -0,0 +1,184 @@
+package probe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRespConnDo(t *testing.T) {
+	tests := map[string]struct {
+		reply  string
+		exp    string
+		expErr string
+	}{
+		"simple string": {reply: "+OK\r\n", exp: "OK"},
+		"error":         {reply: "-ERR unknown command\r\n", expErr: "redis error: ERR unknown command"},
+		"integer":       {reply: ":42\r\n", exp: "42"},
+		"nil bulk":      {reply: "$-1\r\n", expErr: "nil reply"},
+		"bulk string":   {reply: "$12\r\nhello\r\nworld\r\n", exp: "hello\r\nworld"},
+		"empty bulk":    {reply: "$0\r\n\r\n", exp: ""},
+		"unsupported":   {reply: "*1\r\n", expErr: "unsupported reply"},
+		"invalid bulk":  {reply: "$abc\r\n", expErr: "while parsing bulk string length"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			var received []string
+			go serveRedis(server, func(args []string) string {
+				received = args
+				return tc.reply
+			})
+
+			cli := &respConn{rw: client, r: bufio.NewReader(client)}
+			got, err := cli.do("GET", "key")
+
+			switch {
+			case tc.expErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.expErr) {
+					t.Fatalf("got error %v, expected error containing %q", err, tc.expErr)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != tc.exp:
+				t.Errorf("got %q, expected %q", got, tc.exp)
+			}
+			if strings.Join(received, " ") != "GET key" {
+				t.Errorf("server received %q, expected %q", received, "GET key")
+			}
+		})
+	}
+}
+
+func TestRedisProbeCheck(t *testing.T) {
+	tests := map[string]struct {
+		password string
+		expErr   string
+	}{
+		"without password": {},
+		"with password":    {password: "secret"},
+		"wrong password":   {password: "wrong", expErr: "while authenticating: redis error: WRONGPASS invalid password"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go serveRedis(server, fakeRedis("secret"))
+
+			p := &redisProbe{cfg: Config{PasswordKey: "REDIS_PASSWORD"}}
+			err := p.check(client, Credentials{"REDIS_PASSWORD": tc.password})
+
+			switch {
+			case tc.expErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.expErr != "" && (err == nil || err.Error() != tc.expErr):
+				t.Fatalf("got error %v, expected %q", err, tc.expErr)
+			}
+		})
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	cfg := Config{HostKey: "HOST", PortKey: "PORT"}
+
+	tests := map[string]struct {
+		creds  Credentials
+		exp    string
+		expErr bool
+	}{
+		"host name":    {creds: Credentials{"HOST": "redis.svc", "PORT": "6379"}, exp: "redis.svc:6379"},
+		"IPv6 address": {creds: Credentials{"HOST": "fd00::1", "PORT": "6379"}, exp: "[fd00::1]:6379"},
+		"missing port": {creds: Credentials{"HOST": "redis.svc"}, expErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := endpoint(cfg, tc.creds)
+			switch {
+			case tc.expErr:
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != tc.exp:
+				t.Errorf("got %q, expected %q", got, tc.exp)
+			}
+		})
+	}
+}
+
+// serveRedis reads RESP commands from the connection and writes replies returned by the handler until the connection is closed
+func serveRedis(conn net.Conn, handle func(args []string) string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, handle(args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// fakeRedis returns the handler which implements the commands executed by the probe
+func fakeRedis(password string) func(args []string) string {
+	store := map[string]string{}
+
+	return func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if args[1] != password {
+				return "-WRONGPASS invalid password\r\n"
+			}
+			return "+OK\r\n"
+		case "PING":
+			return "+PONG\r\n"
+		case "SET":
+			store[args[1]] = args[2]
+			return "+OK\r\n"
+		case "GET":
+			value, found := store[args[1]]
+			if !found {
+				return "$-1\r\n"
+			}
+			return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+		case "DEL":
+			delete(store, args[1])
+			return ":1\r\n"
+		default:
+			return "-ERR unknown command\r\n"
+		}
+	}
+}
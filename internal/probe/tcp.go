@@ -0,0 +1,27 @@
+package probe
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// tcpProbe checks that the TCP connection to the service can be opened
+type tcpProbe struct {
+	cfg Config
+}
+
+func (p *tcpProbe) Check(ctx context.Context, creds Credentials) error {
+	addr, err := endpoint(p.cfg, creds)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: p.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "while connecting to %s", addr)
+	}
+	return conn.Close()
+}
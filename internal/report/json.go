@@ -0,0 +1,86 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+)
+
+type jsonReport struct {
+	Success bool       `json:"success"`
+	Tests   []jsonTest `json:"tests"`
+}
+
+type jsonTest struct {
+	Name     string    `json:"name"`
+	Runs     int       `json:"runs"`
+	Failures int       `json:"failures"`
+	Duration string    `json:"duration"`
+	Results  []jsonRun `json:"results"`
+}
+
+type jsonRun struct {
+	ID        string       `json:"id"`
+	StartTime time.Time    `json:"startTime"`
+	Duration  string       `json:"duration"`
+	Success   bool         `json:"success"`
+	Error     string       `json:"error,omitempty"`
+	Kind      string       `json:"failureKind,omitempty"`
+	Step      string       `json:"step,omitempty"`
+	Details   []jsonDetail `json:"details,omitempty"`
+	// Retries holds re-runs executed to confirm the failure
+	Retries []jsonRun `json:"retries,omitempty"`
+}
+
+type jsonDetail struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// WriteJSON writes given results in the JSON format
+func WriteJSON(w io.Writer, results []runner.ExecutionResult) error {
+	out := jsonReport{
+		Success: !HasFailures(results),
+		Tests:   []jsonTest{},
+	}
+
+	for _, s := range Summarize(results) {
+		test := jsonTest{
+			Name:     s.Name,
+			Runs:     s.Runs,
+			Failures: s.Failures,
+			Duration: s.Duration.String(),
+		}
+		for _, res := range s.Results {
+			test.Results = append(test.Results, newJSONRun(res))
+		}
+		out.Tests = append(out.Tests, test)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func newJSONRun(res runner.ExecutionResult) jsonRun {
+	run := jsonRun{
+		ID:        res.ID,
+		StartTime: res.StartTime,
+		Duration:  res.Duration.String(),
+		Success:   !res.Failed(),
+	}
+	for _, d := range res.Details {
+		run.Details = append(run.Details, jsonDetail{Name: d.Name, Value: d.Value})
+	}
+	if res.Failed() {
+		run.Error = res.Err.Error()
+		run.Kind = string(res.FailureKind)
+		run.Step = res.Step
+	}
+	for _, retry := range res.Retries {
+		run.Retries = append(run.Retries, newJSONRun(retry))
+	}
+	return run
+}
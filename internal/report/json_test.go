@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	exp := `{
+  "success": false,
+  "tests": [
+    {
+      "name": "Happy Path",
+      "runs": 2,
+      "failures": 1,
+      "duration": "3.5s",
+      "results": [
+        {
+          "id": "id-1",
+          "startTime": "2026-10-19T10:00:00Z",
+          "duration": "1.5s",
+          "success": true,
+          "details": [
+            {
+              "name": "instance",
+              "value": "provisioned in 1s"
+            }
+          ]
+        },
+        {
+          "id": "id-2",
+          "startTime": "2026-10-19T10:01:00Z",
+          "duration": "2s",
+          "success": false,
+          "error": "instance \"redis\" \u003cnot\u003e ready \u0026 failed",
+          "failureKind": "Error",
+          "step": "creating ServiceInstance",
+          "retries": [
+            {
+              "id": "id-3",
+              "startTime": "2026-10-19T10:01:30Z",
+              "duration": "1s",
+              "success": false,
+              "error": "still failing",
+              "failureKind": "Error",
+              "step": "creating ServiceBinding"
+            },
+            {
+              "id": "id-4",
+              "startTime": "2026-10-19T10:01:40Z",
+              "duration": "1s",
+              "success": true
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "Soak",
+      "runs": 1,
+      "failures": 1,
+      "duration": "30m0s",
+      "results": [
+        {
+          "id": "id-5",
+          "startTime": "2026-10-19T10:02:00Z",
+          "duration": "30m0s",
+          "success": false,
+          "error": "test exceeded maximum runtime 30m0s",
+          "failureKind": "Timeout",
+          "step": "verifying pool"
+        }
+      ]
+    }
+  ]
+}
+`
+
+	buf := &bytes.Buffer{}
+	if err := WriteJSON(buf, fixtureResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := buf.String(); got != exp {
+		t.Errorf("got report:\n%s\nexpected:\n%s", got, exp)
+	}
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteJUnit writes given results in the JUnit XML format. Each test is reported as a separate test suite
+// and each execution of the test as a separate test case.
+func WriteJUnit(w io.Writer, results []runner.ExecutionResult) error {
+	out := junitTestSuites{}
+	for _, s := range Summarize(results) {
+		suite := junitTestSuite{
+			Name:     s.Name,
+			Tests:    s.Runs,
+			Failures: s.Failures,
+			Time:     fmt.Sprintf("%.3f", s.Duration.Seconds()),
+		}
+		if len(s.Results) > 0 {
+			suite.Timestamp = s.Results[0].StartTime.UTC().Format("2006-01-02T15:04:05")
+		}
+
+		for i, res := range s.Results {
+			tc := junitTestCase{
+				ClassName: s.Name,
+				Name:      fmt.Sprintf("%s #%d [ID: %s]", s.Name, i+1, res.ID),
+				Time:      fmt.Sprintf("%.3f", res.Duration.Seconds()),
+			}
+			if res.Failed() {
+				tc.Failure = &junitFailure{
+					Message:  fmt.Sprintf("Test failed during step %q", res.Step),
+					Type:     string(res.FailureKind),
+					Contents: res.Err.Error() + retriesDescription(res.Retries),
+				}
+			}
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+
+		out.Suites = append(out.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(out)
+}
+
+// retriesDescription describes re-runs executed to confirm the failure, one per line
+func retriesDescription(retries []runner.ExecutionResult) string {
+	var out string
+	for i, retry := range retries {
+		outcome := "passed"
+		if retry.Failed() {
+			outcome = fmt.Sprintf("failed during step %q: %v", retry.Step, retry.Err)
+		}
+		out += fmt.Sprintf("\nRe-run #%d [ID: %s] %s", i+1, retry.ID, outcome)
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	exp := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="Happy Path" tests="2" failures="1" time="3.500" timestamp="2026-10-19T10:00:00">
+    <testcase classname="Happy Path" name="Happy Path #1 [ID: id-1]" time="1.500"></testcase>
+    <testcase classname="Happy Path" name="Happy Path #2 [ID: id-2]" time="2.000">
+      <failure message="Test failed during step &#34;creating ServiceInstance&#34;" type="Error">instance &#34;redis&#34; &lt;not&gt; ready &amp; failed&#xA;Re-run #1 [ID: id-3] failed during step &#34;creating ServiceBinding&#34;: still failing&#xA;Re-run #2 [ID: id-4] passed</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="Soak" tests="1" failures="1" time="1800.000" timestamp="2026-10-19T10:02:00">
+    <testcase classname="Soak" name="Soak #1 [ID: id-5]" time="1800.000">
+      <failure message="Test failed during step &#34;verifying pool&#34;" type="Timeout">test exceeded maximum runtime 30m0s</failure>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+	buf := &bytes.Buffer{}
+	if err := WriteJUnit(buf, fixtureResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := buf.String(); got != exp {
+		t.Errorf("got report:\n%s\nexpected:\n%s", got, exp)
+	}
+}
+
+// fixtureResults returns results of two tests with passed, failed, timed out and re-run executions
+func fixtureResults() []runner.ExecutionResult {
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	return []runner.ExecutionResult{
+		{
+			TestName:  "Happy Path",
+			ID:        "id-1",
+			StartTime: start,
+			Duration:  1500 * time.Millisecond,
+			Details:   []runner.Detail{{Name: "instance", Value: "provisioned in 1s"}},
+		},
+		{
+			TestName:    "Happy Path",
+			ID:          "id-2",
+			StartTime:   start.Add(time.Minute),
+			Duration:    2 * time.Second,
+			Err:         errors.New(`instance "redis" <not> ready & failed`),
+			FailureKind: runner.FailureKindError,
+			Step:        "creating ServiceInstance",
+			Retries: []runner.ExecutionResult{
+				{ID: "id-3", StartTime: start.Add(90 * time.Second), Duration: time.Second, Err: errors.New("still failing"), FailureKind: runner.FailureKindError, Step: "creating ServiceBinding"},
+				{ID: "id-4", StartTime: start.Add(100 * time.Second), Duration: time.Second},
+			},
+		},
+		{
+			TestName:    "Soak",
+			ID:          "id-5",
+			StartTime:   start.Add(2 * time.Minute),
+			Duration:    30 * time.Minute,
+			Err:         errors.New("test exceeded maximum runtime 30m0s"),
+			FailureKind: runner.FailureKindTimeout,
+			Step:        "verifying pool",
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+)
+
+// TestSummary aggregates the results of all executions of a single test
+type TestSummary struct {
+	Name     string
+	Runs     int
+	Failures int
+	Duration time.Duration
+	Results  []runner.ExecutionResult
+}
+
+// Summarize groups given results by test name. Order of the tests is preserved.
+func Summarize(results []runner.ExecutionResult) []TestSummary {
+	var summaries []TestSummary
+	idx := map[string]int{}
+
+	for _, res := range results {
+		i, found := idx[res.TestName]
+		if !found {
+			i = len(summaries)
+			idx[res.TestName] = i
+			summaries = append(summaries, TestSummary{Name: res.TestName})
+		}
+
+		summaries[i].Runs++
+		summaries[i].Duration += res.Duration
+		summaries[i].Results = append(summaries[i].Results, res)
+		if res.Failed() {
+			summaries[i].Failures++
+		}
+	}
+
+	return summaries
+}
+
+// HasFailures returns true if any of given results failed
+func HasFailures(results []runner.ExecutionResult) bool {
+	for _, res := range results {
+		if res.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintSummary writes human readable summary of given results
+func PrintSummary(w io.Writer, results []runner.ExecutionResult) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEST\tRUNS\tPASSED\tFAILED\tDURATION")
+	for _, s := range Summarize(results) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%v\n", s.Name, s.Runs, s.Runs-s.Failures, s.Failures, s.Duration)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		if res.Failed() {
+			fmt.Fprintf(w, "\n%s %q [ID: %s, step: %q]: %v\n", strings.ToUpper(string(res.FailureKind)), res.TestName, res.ID, res.Step, res.Err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AlertPolicyConfig defines when test failures are reported
+type AlertPolicyConfig struct {
+	// ConsecutiveFailures defines after how many failures in a row the alert is sent
+	ConsecutiveFailures int `envconfig:"default=3"`
+	// FailureRate defines the failure rate in the window above which the alert is sent, e.g. 0.2 means 20%
+	FailureRate float64 `envconfig:"default=0.2"`
+	// Window defines the sliding window in which the failure rate and flakiness are calculated
+	Window time.Duration `envconfig:"default=1h"`
+	// MinRunsInWindow defines how many runs must be in the window to calculate the failure rate
+	MinRunsInWindow int `envconfig:"default=5"`
+	// FlakyTransitions defines after how many changes between success and failure in the window the test is reported as flaky
+	FlakyTransitions int `envconfig:"default=4"`
+}
+
+// AlertDecision holds the policy decision about the recorded test outcome
+type AlertDecision struct {
+	// Alert is true when the failure threshold was crossed
+	Alert bool
+	// Flaky is true when the test started to alternate between success and failure
+	Flaky bool
+	// Reasons describes which thresholds were crossed
+	Reasons []string
+	// Outcomes describes test outcomes in the window, e.g. `✓✗~✓`, where `~` marks the transient failure
+	Outcomes string
+}
+
+// AlertPolicy tracks the sliding window of outcomes per test and decides if failures should be reported.
+// Each threshold is reported only once, when it is crossed, and it is reported again only after the test recovers.
+type AlertPolicy struct {
+	cfg AlertPolicyConfig
+
+	mu      sync.Mutex
+	history map[string]*testHistory
+}
+
+type outcome struct {
+	time      time.Time
+	failed    bool
+	transient bool
+}
+
+type testHistory struct {
+	outcomes            []outcome
+	consecutiveFailures int
+
+	consecutiveAlerted bool
+	rateAlerted        bool
+	flakyReported      bool
+}
+
+// NewAlertPolicy returns new instance of AlertPolicy
+func NewAlertPolicy(cfg AlertPolicyConfig) (*AlertPolicy, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, errors.Wrap(err, "while validating alert policy configuration")
+	}
+
+	return &AlertPolicy{
+		cfg:     cfg,
+		history: map[string]*testHistory{},
+	}, nil
+}
+
+func (cfg AlertPolicyConfig) validate() error {
+	if cfg.ConsecutiveFailures < 1 {
+		return errors.Errorf("consecutive failures must be at least 1, got %d", cfg.ConsecutiveFailures)
+	}
+	if cfg.FailureRate <= 0 || cfg.FailureRate > 1 {
+		return errors.Errorf("failure rate must be in range (0, 1], got %v", cfg.FailureRate)
+	}
+	if cfg.Window <= 0 {
+		return errors.Errorf("window must be greater than 0, got %v", cfg.Window)
+	}
+	if cfg.MinRunsInWindow < 1 {
+		return errors.Errorf("minimal number of runs in window must be at least 1, got %d", cfg.MinRunsInWindow)
+	}
+	// at least two transitions are needed to change from success to failure and back
+	if cfg.FlakyTransitions < 2 {
+		return errors.Errorf("flaky transitions must be at least 2, got %d", cfg.FlakyTransitions)
+	}
+	return nil
+}
+
+// Record records the test outcome and returns the decision whether it should be reported
+func (p *AlertPolicy) Record(result ExecutionResult) AlertDecision {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, found := p.history[result.TestName]
+	if !found {
+		h = &testHistory{}
+		p.history[result.TestName] = h
+	}
+
+	// transient failures are not counted as failures, but they are taken into account when detecting flakiness
+	failed := result.Failed() && !result.Transient()
+	now := result.StartTime.Add(result.Duration)
+	h.outcomes = append(h.outcomes, outcome{time: now, failed: failed, transient: result.Transient()})
+	h.prune(now.Add(-p.cfg.Window))
+
+	decision := AlertDecision{Outcomes: h.outcomesString()}
+
+	runs, failures := len(h.outcomes), h.failures()
+	rate := float64(failures) / float64(runs)
+	if rate <= p.cfg.FailureRate {
+		h.rateAlerted = false
+	}
+
+	// thresholds are evaluated only for failed runs, so the passing run never raises the alert
+	if failed {
+		h.consecutiveFailures++
+		if h.consecutiveFailures >= p.cfg.ConsecutiveFailures && !h.consecutiveAlerted {
+			h.consecutiveAlerted = true
+			decision.Alert = true
+			decision.Reasons = append(decision.Reasons, fmt.Sprintf("%d consecutive failures", h.consecutiveFailures))
+		}
+		if runs >= p.cfg.MinRunsInWindow && rate > p.cfg.FailureRate && !h.rateAlerted {
+			h.rateAlerted = true
+			decision.Alert = true
+			decision.Reasons = append(decision.Reasons, fmt.Sprintf("failure rate %.0f%% (%d of %d runs) in the last %v", rate*100, failures, runs, p.cfg.Window))
+		}
+	} else {
+		h.consecutiveFailures = 0
+		h.consecutiveAlerted = false
+	}
+
+	transitions := h.transitions()
+	switch {
+	case transitions >= p.cfg.FlakyTransitions && !h.flakyReported:
+		h.flakyReported = true
+		decision.Flaky = true
+		decision.Reasons = append(decision.Reasons, fmt.Sprintf("%d changes between success and failure in the last %v", transitions, p.cfg.Window))
+	case transitions < p.cfg.FlakyTransitions:
+		h.flakyReported = false
+	}
+
+	return decision
+}
+
+func (o outcome) unstable() bool {
+	return o.failed || o.transient
+}
+
+func (h *testHistory) prune(before time.Time) {
+	idx := 0
+	for idx < len(h.outcomes) && h.outcomes[idx].time.Before(before) {
+		idx++
+	}
+	h.outcomes = h.outcomes[idx:]
+}
+
+func (h *testHistory) failures() int {
+	cnt := 0
+	for _, o := range h.outcomes {
+		if o.failed {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+func (h *testHistory) transitions() int {
+	cnt := 0
+	for i := 1; i < len(h.outcomes); i++ {
+		if h.outcomes[i].unstable() != h.outcomes[i-1].unstable() {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+func (h *testHistory) outcomesString() string {
+	var b bytes.Buffer
+	for _, o := range h.outcomes {
+		switch {
+		case o.failed:
+			b.WriteString("✗")
+		case o.transient:
+			b.WriteString("~")
+		default:
+			b.WriteString("✓")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlertPolicyRecord(t *testing.T) {
+	type run struct {
+		// outcome is `P` for passed, `F` for failed and `T` for transient failure
+		outcome byte
+		minute  int
+		alert   bool
+		flaky   bool
+	}
+
+	tests := map[string]struct {
+		cfg          AlertPolicyConfig
+		runs         []run
+		lastOutcomes string
+	}{
+		"consecutive failures are reported once until recovery": {
+			cfg: AlertPolicyConfig{ConsecutiveFailures: 3, FailureRate: 1, Window: time.Hour, MinRunsInWindow: 100, FlakyTransitions: 100},
+			runs: []run{
+				{outcome: 'F', minute: 0},
+				{outcome: 'F', minute: 1},
+				{outcome: 'F', minute: 2, alert: true},
+				{outcome: 'F', minute: 3},
+				{outcome: 'P', minute: 4},
+				{outcome: 'F', minute: 5},
+				{outcome: 'F', minute: 6},
+				{outcome: 'F', minute: 7, alert: true},
+			},
+			lastOutcomes: "✗✗✗✗✓✗✗✗",
+		},
+		"passing run never alerts": {
+			cfg: AlertPolicyConfig{ConsecutiveFailures: 3, FailureRate: 0.2, Window: time.Hour, MinRunsInWindow: 5, FlakyTransitions: 4},
+			runs: []run{
+				{outcome: 'F', minute: 0},
+				{outcome: 'F', minute: 1},
+				{outcome: 'F', minute: 2, alert: true},
+				{outcome: 'F', minute: 3},
+				{outcome: 'P', minute: 4},
+				{outcome: 'P', minute: 5},
+			},
+			lastOutcomes: "✗✗✗✗✓✓",
+		},
+		"failure rate is calculated in the sliding window": {
+			cfg: AlertPolicyConfig{ConsecutiveFailures: 100, FailureRate: 0.5, Window: 30 * time.Minute, MinRunsInWindow: 3, FlakyTransitions: 100},
+			runs: []run{
+				{outcome: 'F', minute: 0},
+				{outcome: 'F', minute: 10},
+				{outcome: 'F', minute: 20, alert: true},
+				{outcome: 'F', minute: 30},
+				{outcome: 'P', minute: 40},
+				// window rolled over, rate dropped to 50% and the test recovered
+				{outcome: 'P', minute: 50},
+				{outcome: 'P', minute: 60},
+				{outcome: 'F', minute: 70},
+				{outcome: 'F', minute: 80},
+				{outcome: 'F', minute: 90, alert: true},
+			},
+			lastOutcomes: "✓✗✗✗",
+		},
+		"failure rate requires minimal number of runs": {
+			cfg: AlertPolicyConfig{ConsecutiveFailures: 100, FailureRate: 0.5, Window: time.Hour, MinRunsInWindow: 4, FlakyTransitions: 100},
+			runs: []run{
+				{outcome: 'F', minute: 0},
+				{outcome: 'P', minute: 1},
+				{outcome: 'F', minute: 2},
+				{outcome: 'P', minute: 3},
+				{outcome: 'F', minute: 4, alert: true},
+			},
+			lastOutcomes: "✗✓✗✓✗",
+		},
+		"flaky test is reported once": {
+			cfg: AlertPolicyConfig{ConsecutiveFailures: 100, FailureRate: 1, Window: time.Hour, MinRunsInWindow: 100, FlakyTransitions: 3},
+			runs: []run{
+				{outcome: 'P', minute: 0},
+				{outcome: 'F', minute: 1},
+				{outcome: 'P', minute: 2},
+				{outcome: 'F', minute: 3, flaky: true},
+				{outcome: 'P', minute: 4},
+			},
+			lastOutcomes: "✓✗✓✗✓",
+		},
+		"transient failures count only for flakiness": {
+			cfg: AlertPolicyConfig{ConsecutiveFailures: 1, FailureRate: 0.1, Window: time.Hour, MinRunsInWindow: 1, FlakyTransitions: 3},
+			runs: []run{
+				{outcome: 'P', minute: 0},
+				{outcome: 'T', minute: 1},
+				{outcome: 'P', minute: 2},
+				{outcome: 'T', minute: 3, flaky: true},
+			},
+			lastOutcomes: "✓~✓~",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			policy, err := NewAlertPolicy(tc.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+			var decision AlertDecision
+			for i, r := range tc.runs {
+				result := ExecutionResult{TestName: "test", StartTime: start.Add(time.Duration(r.minute) * time.Minute)}
+				switch r.outcome {
+				case 'F':
+					result.Err = errors.New("failed")
+				case 'T':
+					result.Err = errors.New("failed")
+					result.Retries = []ExecutionResult{{}}
+				}
+
+				decision = policy.Record(result)
+				if decision.Alert != r.alert || decision.Flaky != r.flaky {
+					t.Errorf("run %d: got alert %v and flaky %v, expected alert %v and flaky %v (reasons: %v)", i, decision.Alert, decision.Flaky, r.alert, r.flaky, decision.Reasons)
+				}
+			}
+
+			if decision.Outcomes != tc.lastOutcomes {
+				t.Errorf("got outcomes %q, expected %q", decision.Outcomes, tc.lastOutcomes)
+			}
+		})
+	}
+}
+
+func TestNewAlertPolicyInvalidConfig(t *testing.T) {
+	valid := AlertPolicyConfig{ConsecutiveFailures: 3, FailureRate: 0.2, Window: time.Hour, MinRunsInWindow: 5, FlakyTransitions: 4}
+
+	tests := map[string]func(cfg *AlertPolicyConfig){
+		"zero consecutive failures": func(cfg *AlertPolicyConfig) { cfg.ConsecutiveFailures = 0 },
+		"zero failure rate":         func(cfg *AlertPolicyConfig) { cfg.FailureRate = 0 },
+		"negative failure rate":     func(cfg *AlertPolicyConfig) { cfg.FailureRate = -0.5 },
+		"failure rate above one":    func(cfg *AlertPolicyConfig) { cfg.FailureRate = 1.5 },
+		"zero window":               func(cfg *AlertPolicyConfig) { cfg.Window = 0 },
+		"negative window":           func(cfg *AlertPolicyConfig) { cfg.Window = -time.Minute },
+		"zero min runs in window":   func(cfg *AlertPolicyConfig) { cfg.MinRunsInWindow = 0 },
+		"zero flaky transitions":    func(cfg *AlertPolicyConfig) { cfg.FlakyTransitions = 0 },
+		"one flaky transition":      func(cfg *AlertPolicyConfig) { cfg.FlakyTransitions = 1 },
+	}
+
+	if _, err := NewAlertPolicy(valid); err != nil {
+		t.Fatalf("unexpected error for valid configuration: %v", err)
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := valid
+			modify(&cfg)
+			if _, err := NewAlertPolicy(cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -1,9 +1,63 @@
 package runner
 
+import (
+	"context"
+	"time"
+)
+
 type (
 	// Test allows to execute test in a generic way
 	Test interface {
-		Execute(stop <-chan struct{}) error
+		Execute(ctx context.Context) error
 		Name() string
 	}
+
+	// ConfiguredTest binds the test with its schedule configuration
+	ConfiguredTest struct {
+		Test     Test
+		Schedule ScheduleConfig
+	}
+
+	// ExecutionResult holds the outcome of a single test execution
+	ExecutionResult struct {
+		TestName  string
+		ID        string
+		StartTime time.Time
+		Duration  time.Duration
+		Err       error
+		// FailureKind is empty when test passed
+		FailureKind FailureKind
+		// Step is the test step which was in progress when test ended
+		Step string
+		// Details holds additional information recorded by the test
+		Details []Detail
+		// Retries holds results of the re-runs executed to confirm the failure
+		Retries []ExecutionResult
+	}
+
+	// Detail is a named information recorded during the test execution
+	Detail struct {
+		Name  string
+		Value string
+	}
+
+	// FailureKind describes why the test execution failed
+	FailureKind string
+)
+
+const (
+	// FailureKindError is set when the test returned an error
+	FailureKindError FailureKind = "Error"
+	// FailureKindTimeout is set when the test exceeded its maximum runtime
+	FailureKindTimeout FailureKind = "Timeout"
 )
+
+// Failed returns true if test execution ended with error
+func (r ExecutionResult) Failed() bool {
+	return r.Err != nil
+}
+
+// Transient returns true if the failure was not confirmed, because one of the re-runs passed
+func (r ExecutionResult) Transient() bool {
+	return r.Failed() && len(r.Retries) > 0 && !r.Retries[len(r.Retries)-1].Failed()
+}
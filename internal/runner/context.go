@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CleanupTimeout is the time in which the test must clean up after it ends, also when its context is canceled
+const CleanupTimeout = 10 * time.Minute
+
+type (
+	runIDKey       struct{}
+	stepTrackerKey struct{}
+)
+
+func withRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunID returns the ID of the test execution, under which the test outcome is logged and reported.
+// Empty string is returned when test is not executed by the runner.
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// CleanupContext returns the context for the clean-up of the test executed with given context. It keeps the run ID
+// and the step tracker, but it is not canceled together with the test context, so the test cleans up also after
+// it exceeded its maximum runtime. The returned context is canceled after the CleanupTimeout.
+func CleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if tracker, ok := ctx.Value(stepTrackerKey{}).(*stepTracker); ok && ctx.Err() != nil {
+		tracker.interrupt()
+	}
+	return context.WithTimeout(valuesOnlyContext{ctx}, CleanupTimeout)
+}
+
+// valuesOnlyContext passes values of the parent context, but not its deadline and cancellation
+type valuesOnlyContext struct {
+	context.Context
+}
+
+func (valuesOnlyContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesOnlyContext) Done() <-chan struct{}       { return nil }
+func (valuesOnlyContext) Err() error                  { return nil }
+
+// stepTracker holds the step of the test which is currently in progress and details recorded by the test
+type stepTracker struct {
+	mu      sync.RWMutex
+	current string
+	details []Detail
+	// interrupted holds the step which was in progress when the test context was canceled
+	interrupted string
+}
+
+func withStepTracker(ctx context.Context) (context.Context, *stepTracker) {
+	tracker := &stepTracker{}
+	return context.WithValue(ctx, stepTrackerKey{}, tracker), tracker
+}
+
+// StartStep marks given step as currently in progress for the test executed with given context.
+// The step is reported when the test fails or exceeds its maximum runtime.
+func StartStep(ctx context.Context, name string) {
+	tracker, ok := ctx.Value(stepTrackerKey{}).(*stepTracker)
+	if !ok {
+		return
+	}
+
+	tracker.mu.Lock()
+	tracker.current = name
+	tracker.mu.Unlock()
+}
+
+func (t *stepTracker) step() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.current
+}
+
+// interrupt records the current step as interrupted, unless it was already recorded,
+// so the step is not replaced by clean-up steps executed after the cancellation
+func (t *stepTracker) interrupt() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.interrupted == "" {
+		t.interrupted = t.current
+	}
+}
+
+func (t *stepTracker) interruptedStep() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.interrupted
+}
+
+// RecordDetail records additional information about the test execution, e.g. measured latency.
+// Details are logged and reported together with the test outcome.
+func RecordDetail(ctx context.Context, name, value string) {
+	tracker, ok := ctx.Value(stepTrackerKey{}).(*stepTracker)
+	if !ok {
+		return
+	}
+
+	tracker.mu.Lock()
+	tracker.details = append(tracker.details, Detail{Name: name, Value: value})
+	tracker.mu.Unlock()
+}
+
+func (t *stepTracker) recordedDetails() []Detail {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return append([]Detail(nil), t.details...)
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"sync"
+	"time"
+)
+
+// testControl allows to steer the execution loop of a single test at runtime
+type testControl struct {
+	mu       sync.RWMutex
+	paused   bool
+	schedule Schedule
+	jitter   time.Duration
+	nextRun  time.Time
+
+	// triggerCh and changedCh are buffered, so sending signal never blocks
+	// and multiple signals sent during test execution are collapsed into one
+	triggerCh chan struct{}
+	changedCh chan struct{}
+}
+
+// TestState describes the current state of the test execution loop
+type TestState struct {
+	Name     string     `json:"name"`
+	Paused   bool       `json:"paused"`
+	Schedule string     `json:"schedule"`
+	Jitter   string     `json:"jitter"`
+	NextRun  *time.Time `json:"nextRun,omitempty"`
+}
+
+func newTestControl(schedule Schedule, jitter time.Duration) *testControl {
+	return &testControl{
+		schedule:  schedule,
+		jitter:    jitter,
+		triggerCh: make(chan struct{}, 1),
+		changedCh: make(chan struct{}, 1),
+	}
+}
+
+func (c *testControl) trigger() {
+	select {
+	case c.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+func (c *testControl) pause() {
+	c.mu.Lock()
+	c.paused = true
+	c.mu.Unlock()
+
+	c.notifyChanged()
+}
+
+func (c *testControl) resume() {
+	c.mu.Lock()
+	c.paused = false
+	c.mu.Unlock()
+
+	c.notifyChanged()
+}
+
+// setThrottle replaces the current schedule, also the cron one, with the fixed throttle
+func (c *testControl) setThrottle(throttle time.Duration) {
+	c.mu.Lock()
+	c.schedule = &throttleSchedule{throttle: throttle}
+	c.mu.Unlock()
+
+	c.notifyChanged()
+}
+
+// planNextRun calculates the next execution time based on the time when the previous execution finished.
+// Zero time is returned when test is paused.
+func (c *testControl) planNextRun(finished time.Time) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		c.nextRun = time.Time{}
+	} else {
+		c.nextRun = withJitter(c.schedule.Next(finished), c.jitter)
+	}
+
+	return c.nextRun
+}
+
+func (c *testControl) clearNextRun() {
+	c.mu.Lock()
+	c.nextRun = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *testControl) state(name string) TestState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	state := TestState{
+		Name:     name,
+		Paused:   c.paused,
+		Schedule: c.schedule.String(),
+		Jitter:   c.jitter.String(),
+	}
+	if !c.nextRun.IsZero() {
+		next := c.nextRun
+		state.NextRun = &next
+	}
+
+	return state
+}
+
+func (c *testControl) notifyChanged() {
+	select {
+	case c.changedCh <- struct{}{}:
+	default:
+	}
+}
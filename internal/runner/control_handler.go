@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ControlHandler exposes HTTP endpoints which allow to steer tests executed by the StressTestRunner:
+// - GET  /tests                                - lists tests with their current state
+// - POST /tests/trigger?name={name}            - executes given test immediately, also when test is paused
+// - POST /tests/pause?name={name}              - stops executing given test in a loop
+// - POST /tests/resume?name={name}             - resumes executing given test in a loop
+// - POST /tests/throttle?name={name}&value=30s - replaces the schedule of given test with the fixed throttle
+type ControlHandler struct {
+	runner *StressTestRunner
+	log    logrus.FieldLogger
+}
+
+// NewControlHandler returns new instance of ControlHandler
+func NewControlHandler(runner *StressTestRunner, log logrus.FieldLogger) *ControlHandler {
+	return &ControlHandler{
+		runner: runner,
+		log:    log.WithField("service", "test:control-handler"),
+	}
+}
+
+// RegisterRoutes registers control endpoints in given mux
+func (h *ControlHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/tests", h.listTests)
+	mux.HandleFunc("/tests/trigger", h.withControl(func(req *http.Request, ctrl *testControl) error {
+		ctrl.trigger()
+		return nil
+	}))
+	mux.HandleFunc("/tests/pause", h.withControl(func(req *http.Request, ctrl *testControl) error {
+		ctrl.pause()
+		return nil
+	}))
+	mux.HandleFunc("/tests/resume", h.withControl(func(req *http.Request, ctrl *testControl) error {
+		ctrl.resume()
+		return nil
+	}))
+	mux.HandleFunc("/tests/throttle", h.withControl(func(req *http.Request, ctrl *testControl) error {
+		throttle, err := time.ParseDuration(req.URL.Query().Get("value"))
+		if err != nil {
+			return errors.Wrap(err, "while parsing throttle value")
+		}
+		if throttle <= 0 {
+			return errors.Errorf("throttle value must be greater than zero, got %v", throttle)
+		}
+		ctrl.setThrottle(throttle)
+		return nil
+	}))
+}
+
+func (h *ControlHandler) listTests(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s is not allowed", req.Method))
+		return
+	}
+
+	h.writeStates(w, h.runner.States())
+}
+
+// withControl resolves the test control from the `name` query parameter, executes given action on it
+// and responds with the test state
+func (h *ControlHandler) withControl(action func(req *http.Request, ctrl *testControl) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			h.writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s is not allowed", req.Method))
+			return
+		}
+
+		name := req.URL.Query().Get("name")
+		ctrl, found := h.runner.control(name)
+		if !found {
+			h.writeError(w, http.StatusNotFound, fmt.Sprintf("Test %q is not executed by runner", name))
+			return
+		}
+
+		if err := action(req, ctrl); err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		h.log.Infof("Executed %s action for test %q", req.URL.Path, name)
+		h.writeStates(w, []TestState{ctrl.state(name)})
+	}
+}
+
+func (h *ControlHandler) writeStates(w http.ResponseWriter, states []TestState) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(states); err != nil {
+		h.log.Errorf("Got error while encoding response: %v", err)
+	}
+}
+
+func (h *ControlHandler) writeError(w http.ResponseWriter, code int, msg string) {
+	w.WriteHeader(code)
+	fmt.Fprint(w, msg)
+}
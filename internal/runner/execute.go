@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// cleanupGracePeriod is how long the runner waits for the canceled test. It is longer than the CleanupTimeout,
+// so errors of the clean-up started with the CleanupContext are reported.
+const cleanupGracePeriod = CleanupTimeout + time.Minute
+
+// executeTest executes given test once and logs its outcome under the generated test ID.
+// When maxRuntime is greater than zero, the test context is canceled after that time and the test is reported
+// as timed out. The runner waits until the canceled test cleans up, at most for the cleanupGracePeriod,
+// so the next execution does not start while the previous one still removes its resources.
+func executeTest(stopCh <-chan struct{}, test Test, maxRuntime time.Duration, log logrus.FieldLogger) ExecutionResult {
+	testID := generateTestID()
+	testLogger := log.WithField("ID", testID)
+	testLogger.Infof("Starting test %q", test.Name())
+
+	ctx, cancel := contextWithStop(stopCh, maxRuntime)
+	defer cancel()
+	ctx, tracker := withStepTracker(withRunID(ctx, testID))
+
+	startTime := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- test.Execute(ctx)
+	}()
+
+	var (
+		err  error
+		step string
+	)
+	select {
+	case err = <-errCh:
+		step = tracker.step()
+	case <-ctx.Done():
+		// the test may already clean up with the CleanupContext, which records the interrupted step itself
+		tracker.interrupt()
+		step = tracker.interruptedStep()
+		testLogger.Infof("Test %q canceled during step %q, waiting until it cleans up", test.Name(), step)
+		select {
+		case err = <-errCh:
+		case <-time.After(cleanupGracePeriod):
+			err = errors.Errorf("test did not return in %v after it was canceled", cleanupGracePeriod)
+		}
+	}
+	duration := time.Since(startTime)
+
+	result := ExecutionResult{
+		TestName:  test.Name(),
+		ID:        testID,
+		StartTime: startTime,
+		Duration:  duration,
+		Step:      step,
+		Details:   tracker.recordedDetails(),
+	}
+	for _, d := range result.Details {
+		testLogger.WithField("detail", d.Name).Info(d.Value)
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.FailureKind = FailureKindTimeout
+		result.Err = errors.Errorf("test exceeded maximum runtime %v during step %q: %v", maxRuntime, result.Step, err)
+		testLogger.Errorf("Test %q timed out [start time: %v, duration: %v]: %v", test.Name(), startTime, duration, result.Err)
+	case err == nil && ctx.Err() != nil:
+		result.FailureKind = FailureKindError
+		result.Err = ctx.Err()
+		testLogger.Errorf("Test %q canceled during step %q [start time: %v, duration: %v]", test.Name(), result.Step, startTime, duration)
+	case err != nil:
+		result.FailureKind = FailureKindError
+		result.Err = err
+		testLogger.Errorf("Test %q end with error during step %q [start time: %v, duration: %v]: %v", test.Name(), result.Step, startTime, duration, err)
+	default:
+		testLogger.Infof("Test %q end with success [start time: %v, duration: %v]", test.Name(), startTime, duration)
+	}
+
+	return result
+}
+
+// contextWithStop returns context which is canceled when given stop channel is closed
+// or when maxRuntime passes, if it is greater than zero
+func contextWithStop(stopCh <-chan struct{}, maxRuntime time.Duration) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if maxRuntime > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), maxRuntime)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// generateTestID generates random test ID
+func generateTestID() string {
+	return uuid.NewV4().String()
+}
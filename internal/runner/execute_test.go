@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// testFunc adapts the function to the Test interface
+type testFunc func(ctx context.Context) error
+
+func (f testFunc) Execute(ctx context.Context) error { return f(ctx) }
+func (f testFunc) Name() string                      { return "test" }
+
+func TestExecuteTestWaitsForCleanup(t *testing.T) {
+	test := testFunc(func(ctx context.Context) (retErr error) {
+		defer func() {
+			ctx, cancel := CleanupContext(ctx)
+			defer cancel()
+
+			StartStep(ctx, "cleaning up")
+			if err := ctx.Err(); err != nil {
+				retErr = errors.Wrap(err, "clean-up context is canceled")
+				return
+			}
+			RecordDetail(ctx, "clean-up", "done")
+			retErr = errors.Errorf("%v\ndiagnostic bundle: %s", retErr, "bundle.tar.gz")
+		}()
+
+		StartStep(ctx, "waiting")
+		<-ctx.Done()
+		return errors.Wrap(ctx.Err(), "while waiting")
+	})
+
+	result := executeTest(nil, test, 10*time.Millisecond, logrus.New())
+
+	if result.FailureKind != FailureKindTimeout {
+		t.Errorf("got failure kind %q, expected %q", result.FailureKind, FailureKindTimeout)
+	}
+	if result.Step != "waiting" {
+		t.Errorf("got step %q, expected %q", result.Step, "waiting")
+	}
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "diagnostic bundle: bundle.tar.gz") {
+		t.Errorf("got error %v, expected error returned by the clean-up", result.Err)
+	}
+	if len(result.Details) != 1 || result.Details[0] != (Detail{Name: "clean-up", Value: "done"}) {
+		t.Errorf("got details %v, expected detail recorded by the clean-up", result.Details)
+	}
+}
+
+func TestExecuteTestCanceledByStop(t *testing.T) {
+	stopCh := make(chan struct{})
+	close(stopCh)
+
+	test := testFunc(func(ctx context.Context) error {
+		StartStep(ctx, "waiting")
+		<-ctx.Done()
+		return nil
+	})
+
+	result := executeTest(stopCh, test, time.Minute, logrus.New())
+
+	if result.FailureKind != FailureKindError || result.Err != context.Canceled {
+		t.Errorf("got failure kind %q and error %v, expected %q and %v", result.FailureKind, result.Err, FailureKindError, context.Canceled)
+	}
+}
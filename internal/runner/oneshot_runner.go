@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// OneShotRunner executes given tests requested number of times and returns their results.
+// In contrast to the StressTestRunner, it does not send any notifications.
+type OneShotRunner struct {
+	log logrus.FieldLogger
+}
+
+// NewOneShotRunner is a constructor for OneShotRunner
+func NewOneShotRunner(log logrus.FieldLogger) *OneShotRunner {
+	return &OneShotRunner{
+		log: log.WithField("service", "test:one-shot-runner"),
+	}
+}
+
+// Run executes each test `count` times one after another. Only the MaxRuntime is taken from the test schedule.
+// Tests which were not executed because of shutdown request are not present in returned results.
+func (r *OneShotRunner) Run(stopCh <-chan struct{}, count int, tests ...ConfiguredTest) []ExecutionResult {
+	var results []ExecutionResult
+	for _, t := range tests {
+		for i := 1; i <= count; i++ {
+			select {
+			case <-stopCh:
+				r.log.Debug("Stop channel called. Shutdown test runner")
+				return results
+			default:
+			}
+
+			r.log.Infof("Executing test %q [%d/%d]", t.Test.Name(), i, count)
+			results = append(results, executeTest(stopCh, t.Test, t.Schedule.MaxRuntime, r.log))
+		}
+	}
+
+	return results
+}
@@ -2,16 +2,31 @@ package runner
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/satori/go.uuid"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// Config holds configuration of the StressTestRunner
+type Config struct {
+	// ConfirmFailureRetries defines how many times the failed test is re-run immediately to confirm the failure.
+	// Zero disables confirming failures.
+	ConfirmFailureRetries int `envconfig:"default=0"`
+}
+
 // StressTestRunner is a test runner
 type StressTestRunner struct {
+	cfg           Config
 	log           logrus.FieldLogger
 	slackNotifier SlackNotifier
+	alertPolicy   *AlertPolicy
+
+	controlsMu sync.RWMutex
+	controls   map[string]*testControl
 }
 
 // SlackNotifier allows sending notification about messages to Slack channel.
@@ -20,46 +35,187 @@ type SlackNotifier interface {
 }
 
 // NewStressTestRunner is a constructor for StressTestRunner
-func NewStressTestRunner(slackNotifier SlackNotifier, log logrus.FieldLogger) *StressTestRunner {
+func NewStressTestRunner(cfg Config, slackNotifier SlackNotifier, alertPolicy *AlertPolicy, log logrus.FieldLogger) *StressTestRunner {
 	return &StressTestRunner{
+		cfg:           cfg,
 		log:           log.WithField("service", "test:runner"),
 		slackNotifier: slackNotifier,
+		alertPolicy:   alertPolicy,
+		controls:      map[string]*testControl{},
 	}
 }
 
-// Run executes given test in a loop with given throttle
-func (r *StressTestRunner) Run(stopCh <-chan struct{}, throttle time.Duration, test Test) error {
+// Run executes given test in a loop according to given schedule.
+// The loop can be steered at runtime by the ControlHandler.
+func (r *StressTestRunner) Run(stopCh <-chan struct{}, cfg ScheduleConfig, test Test) error {
+	schedule, err := NewSchedule(cfg)
+	if err != nil {
+		return errors.Wrapf(err, "while creating schedule for test %q", test.Name())
+	}
+
+	ctrl, err := r.registerControl(test.Name(), schedule, cfg.Jitter)
+	if err != nil {
+		return err
+	}
+	defer r.unregisterControl(test.Name())
+
+	if canceled := r.waitForFirstRun(stopCh, test.Name(), ctrl, cfg); canceled {
+		return nil
+	}
+
 	for {
 		if r.shutdownRequested(stopCh) {
 			return nil
 		}
 
-		testID := r.generateTestID()
-		testLogger := r.log.WithField("ID", testID)
-		testLogger.Infof("Starting test %q", test.Name())
+		result := executeTest(stopCh, test, cfg.MaxRuntime, r.log)
+		if result.Failed() {
+			result = r.confirmFailure(stopCh, test, cfg.MaxRuntime, result)
+		}
+		if !r.shutdownRequested(stopCh) {
+			r.handleResult(result)
+		}
 
-		startTime := time.Now()
-		if err := test.Execute(stopCh); err != nil {
-			testLogger.Errorf("Test %q end with error [start time: %v, duration: %v]: %v", test.Name(), startTime, time.Since(startTime), err)
+		if canceled := r.waitForNextRun(stopCh, test.Name(), ctrl, time.Now()); canceled {
+			return nil
+		}
+	}
+}
 
-			failureReasonHeader := fmt.Sprintf("*[Phase: TESTING]* _Stress tests *%s* failed_", test.Name())
-			if err := r.slackNotifier.Notify(testID, failureReasonHeader, err.Error()); err != nil {
-				testLogger.Errorf("Got error when sending Slack notification: %v", err)
-			}
-		} else {
-			testLogger.Infof("Test %q end with success [start time: %v, duration: %v]", test.Name(), startTime, time.Since(startTime))
+// confirmFailure re-runs the failed test up to the configured number of times and stops on the first success.
+// The re-runs results are attached to the given result.
+func (r *StressTestRunner) confirmFailure(stopCh <-chan struct{}, test Test, maxRuntime time.Duration, result ExecutionResult) ExecutionResult {
+	testLogger := r.log.WithField("ID", result.ID)
+	for i := 1; i <= r.cfg.ConfirmFailureRetries; i++ {
+		if r.shutdownRequested(stopCh) {
+			return result
 		}
 
-		r.log.Infof("Throttle test %s for %v", test.Name(), throttle)
-		if canceled := r.throttleTest(stopCh, throttle); canceled {
-			return nil
+		testLogger.Infof("Re-running failed test %q to confirm the failure [%d/%d]", test.Name(), i, r.cfg.ConfirmFailureRetries)
+		retry := executeTest(stopCh, test, maxRuntime, r.log)
+		result.Retries = append(result.Retries, retry)
+		if !retry.Failed() {
+			testLogger.Infof("Failure of test %q is transient, re-run %s passed", test.Name(), retry.ID)
+			return result
 		}
 	}
+
+	if len(result.Retries) > 0 {
+		testLogger.Infof("Failure of test %q confirmed by %d re-runs", test.Name(), len(result.Retries))
+	}
+	return result
+}
+
+// handleResult consults the alert policy and sends notification only when the policy thresholds are crossed
+func (r *StressTestRunner) handleResult(result ExecutionResult) {
+	decision := r.alertPolicy.Record(result)
+	testLogger := r.log.WithField("ID", result.ID)
+	testLogger.Debugf("Outcomes of test %q in the window: %s", result.TestName, decision.Outcomes)
+
+	switch {
+	case decision.Alert:
+		r.notifyFailure(result, decision)
+	case decision.Flaky:
+		r.notifyFlaky(result, decision)
+	case result.Transient():
+		testLogger.Infof("Failure of test %q is transient, notification not sent", result.TestName)
+	case result.Failed():
+		testLogger.Infof("Failure of test %q is below the alert thresholds, notification not sent", result.TestName)
+	}
 }
 
-// generateTestID generates random test ID
-func (r *StressTestRunner) generateTestID() string {
-	return uuid.NewV4().String()
+func (r *StressTestRunner) notifyFailure(result ExecutionResult, decision AlertDecision) {
+	failureReasonHeader := fmt.Sprintf("*[Phase: TESTING]* _Stress tests *%s* failed_", result.TestName)
+	if result.FailureKind == FailureKindTimeout {
+		failureReasonHeader = fmt.Sprintf("*[Phase: TESTING]* _Stress tests *%s* timed out_", result.TestName)
+	}
+
+	details := fmt.Sprintf("Alert reason: %s\nRecent outcomes: %s\n", strings.Join(decision.Reasons, ", "), decision.Outcomes)
+	if len(result.Retries) > 0 {
+		details += fmt.Sprintf("Failure confirmed by %d re-runs\n", len(result.Retries))
+	}
+	details += r.failureDetails(result)
+	for i, retry := range result.Retries {
+		details += fmt.Sprintf("\n*Re-run %d* [ID: %s]\n%s", i+1, retry.ID, r.failureDetails(retry))
+	}
+
+	r.notify(result.ID, failureReasonHeader, details)
+}
+
+func (r *StressTestRunner) notifyFlaky(result ExecutionResult, decision AlertDecision) {
+	header := fmt.Sprintf("*[Phase: TESTING]* _Stress tests *%s* are flaky_", result.TestName)
+	details := fmt.Sprintf("Flakiness reason: %s\nRecent outcomes: %s", strings.Join(decision.Reasons, ", "), decision.Outcomes)
+	if result.Failed() {
+		details += fmt.Sprintf("\nLast error: %v", result.Err)
+	}
+	if result.Transient() {
+		details += fmt.Sprintf("\nFailure was transient, re-run %s passed", result.Retries[len(result.Retries)-1].ID)
+	}
+
+	r.notify(result.ID, header, details)
+}
+
+func (r *StressTestRunner) failureDetails(result ExecutionResult) string {
+	details := "no error"
+	if result.Err != nil {
+		details = result.Err.Error()
+	}
+	if result.Step != "" {
+		details = fmt.Sprintf("Step in progress: %s\n%s", result.Step, details)
+	}
+	for _, d := range result.Details {
+		details += fmt.Sprintf("\n%s: %s", d.Name, d.Value)
+	}
+	return details
+}
+
+func (r *StressTestRunner) notify(id, header, details string) {
+	if err := r.slackNotifier.Notify(id, header, details); err != nil {
+		r.log.WithField("ID", id).Errorf("Got error when sending Slack notification: %v", err)
+	}
+}
+
+// States returns the current state of all tests executed by runner
+func (r *StressTestRunner) States() []TestState {
+	r.controlsMu.RLock()
+	defer r.controlsMu.RUnlock()
+
+	var states []TestState
+	for name, ctrl := range r.controls {
+		states = append(states, ctrl.state(name))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+
+	return states
+}
+
+func (r *StressTestRunner) registerControl(name string, schedule Schedule, jitter time.Duration) (*testControl, error) {
+	r.controlsMu.Lock()
+	defer r.controlsMu.Unlock()
+
+	if _, found := r.controls[name]; found {
+		return nil, errors.Errorf("test %q is already executed by runner", name)
+	}
+
+	ctrl := newTestControl(schedule, jitter)
+	r.controls[name] = ctrl
+
+	return ctrl, nil
+}
+
+func (r *StressTestRunner) unregisterControl(name string) {
+	r.controlsMu.Lock()
+	defer r.controlsMu.Unlock()
+
+	delete(r.controls, name)
+}
+
+func (r *StressTestRunner) control(name string) (*testControl, bool) {
+	r.controlsMu.RLock()
+	defer r.controlsMu.RUnlock()
+
+	ctrl, found := r.controls[name]
+	return ctrl, found
 }
 
 func (r *StressTestRunner) shutdownRequested(stopCh <-chan struct{}) bool {
@@ -73,13 +229,49 @@ func (r *StressTestRunner) shutdownRequested(stopCh <-chan struct{}) bool {
 	return false
 }
 
-func (r *StressTestRunner) throttleTest(stopCh <-chan struct{}, throttle time.Duration) bool {
-	select {
-	case <-stopCh:
-		r.log.Debug("Stop channel called. Shutdown test runner")
-		return true
-	case <-time.After(throttle):
+// waitForFirstRun delays the first execution of the cron scheduled tests until the first activation time
+// and spreads the first execution of the throttled tests by jitter, so they do not start at the same time.
+// Returns true when runner shutdown was requested.
+func (r *StressTestRunner) waitForFirstRun(stopCh <-chan struct{}, name string, ctrl *testControl, cfg ScheduleConfig) bool {
+	if cfg.Cron != "" {
+		return r.waitForNextRun(stopCh, name, ctrl, time.Now())
+	}
+	if cfg.Jitter <= 0 {
+		return false
 	}
 
-	return false
+	// throttle is subtracted so the next run is planned only with jitter delay
+	return r.waitForNextRun(stopCh, name, ctrl, time.Now().Add(-cfg.Throttle))
+}
+
+// waitForNextRun blocks until the next test execution should be started.
+// Returns true when runner shutdown was requested.
+func (r *StressTestRunner) waitForNextRun(stopCh <-chan struct{}, name string, ctrl *testControl, finished time.Time) bool {
+	defer ctrl.clearNextRun()
+
+	for {
+		// nil channel blocks forever, so paused test is executed only when it is triggered manually
+		var nextRunCh <-chan time.Time
+
+		nextRun := ctrl.planNextRun(finished)
+		if nextRun.IsZero() {
+			r.log.Infof("Test %s is paused", name)
+		} else {
+			left := time.Until(nextRun)
+			r.log.Infof("Next execution of test %s planned at %v (in %v)", name, nextRun, left)
+			nextRunCh = time.After(left)
+		}
+
+		select {
+		case <-stopCh:
+			r.log.Debug("Stop channel called. Shutdown test runner")
+			return true
+		case <-ctrl.triggerCh:
+			r.log.Infof("Test %s triggered manually", name)
+			return false
+		case <-ctrl.changedCh:
+		case <-nextRunCh:
+			return false
+		}
+	}
 }
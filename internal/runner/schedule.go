@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/cron"
+	"github.com/pkg/errors"
+)
+
+// ScheduleConfig defines when and how long the test is executed
+type ScheduleConfig struct {
+	// Throttle defines the time after which the next test is executed. Ignored when Cron is set.
+	Throttle time.Duration `envconfig:"default=60s"`
+	// Cron defines the test schedule in the standard cron format, e.g. `0 2 * * *`
+	Cron string `envconfig:"optional"`
+	// Jitter defines the maximum random delay added to each scheduled execution
+	Jitter time.Duration `envconfig:"optional"`
+	// MaxRuntime defines the maximum duration of a single test execution. Zero means no limit.
+	MaxRuntime time.Duration `envconfig:"default=30m"`
+}
+
+// Schedule calculates the next execution time of the test
+type Schedule interface {
+	// Next returns the time of the next execution, given the time when the previous one finished
+	Next(finished time.Time) time.Time
+	String() string
+}
+
+// NewSchedule returns the Schedule described by given configuration
+func NewSchedule(cfg ScheduleConfig) (Schedule, error) {
+	if cfg.Cron == "" {
+		return &throttleSchedule{throttle: cfg.Throttle}, nil
+	}
+
+	spec, err := cron.Parse(cfg.Cron)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing cron expression %q", cfg.Cron)
+	}
+	if spec.Next(time.Now()).IsZero() {
+		return nil, errors.Errorf("cron expression %q never activates", cfg.Cron)
+	}
+
+	return &cronSchedule{expr: cfg.Cron, spec: spec}, nil
+}
+
+type throttleSchedule struct {
+	throttle time.Duration
+}
+
+func (s *throttleSchedule) Next(finished time.Time) time.Time {
+	return finished.Add(s.throttle)
+}
+
+func (s *throttleSchedule) String() string {
+	return fmt.Sprintf("every %v", s.throttle)
+}
+
+type cronSchedule struct {
+	expr string
+	spec *cron.Schedule
+}
+
+func (s *cronSchedule) Next(finished time.Time) time.Time {
+	return s.spec.Next(finished)
+}
+
+func (s *cronSchedule) String() string {
+	return fmt.Sprintf("cron %q", s.expr)
+}
+
+// withJitter returns the given time delayed by random duration from [0, jitter) range
+func withJitter(t time.Time, jitter time.Duration) time.Time {
+	if jitter <= 0 {
+		return t
+	}
+	return t.Add(time.Duration(rand.Int63n(int64(jitter))))
+}
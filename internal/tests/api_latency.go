@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/stats"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	restclient "k8s.io/client-go/rest"
+)
+
+// apiLatencyClassExternalName is referenced by created ServiceInstances. The class does not exist,
+// so the controller-manager never calls any broker and the test loads only the Service Catalog API server.
+const apiLatencyClassExternalName = "service-catalog-tester-nonexistent-class"
+
+// APILatencyTest probes the Service Catalog API server with lightweight requests:
+// - listing and getting ClusterServiceClasses
+// - creating, getting, listing and deleting ServiceInstances in the scratch namespace
+//
+// The latency and the error rate are recorded per request kind. The test fails when any of them exceeds the threshold,
+// so the slowness of the aggregated API server is detected even when the E2E tests are throttled.
+type APILatencyTest struct {
+	cfg          APILatencyTestConfig
+	k8sClientCfg *restclient.Config
+	latency      LatencyObserver
+	log          logrus.FieldLogger
+}
+
+// APILatencyTestConfig holds possible configuration for test
+type APILatencyTestConfig struct {
+	Enabled bool `envconfig:"default=false"`
+	// Iterations is the number of request sequences executed in a single run
+	Iterations int           `envconfig:"default=10"`
+	Interval   time.Duration `envconfig:"default=1s"`
+	// Thresholds, the zero value disables the check
+	MaxErrorRate float64       `envconfig:"default=0.05"`
+	MaxP90       time.Duration `envconfig:"default=1s"`
+	MaxP99       time.Duration `envconfig:"default=3s"`
+	Test         runner.ScheduleConfig
+}
+
+// NewAPILatencyTest returns new instance of APILatencyTest
+func NewAPILatencyTest(cfg APILatencyTestConfig, latency LatencyObserver, k8sClientCfg *restclient.Config, log logrus.FieldLogger) *APILatencyTest {
+	return &APILatencyTest{
+		cfg:          cfg,
+		k8sClientCfg: k8sClientCfg,
+		latency:      latency,
+		log:          log.WithField("service", "test:api-latency"),
+	}
+}
+
+// apiRequest holds measurements of a single kind of the API server request
+type apiRequest struct {
+	resource  string
+	verb      string
+	latencies stats.Latencies
+	calls     int
+	errs      []string
+}
+
+// Execute executes the API server latency probe
+func (t *APILatencyTest) Execute(ctx context.Context) (retErr error) {
+	// setup
+	runner.StartStep(ctx, "creating test suite")
+	ts, err := newTestSuite(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating test suite")
+	}
+
+	runner.StartStep(ctx, "creating test namespace")
+	if err := ts.createTestNamespace(ctx); err != nil {
+		return errors.Wrap(err, "while creating test namespace")
+	}
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		runner.StartStep(ctx, "deleting test namespace")
+		if err := ts.ensureTestNamespaceIsDeleted(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
+		}
+	}()
+
+	var (
+		listCSC  = &apiRequest{resource: "clusterserviceclasses", verb: "list"}
+		getCSC   = &apiRequest{resource: "clusterserviceclasses", verb: "get"}
+		createSI = &apiRequest{resource: "serviceinstances", verb: "create"}
+		getSI    = &apiRequest{resource: "serviceinstances", verb: "get"}
+		listSI   = &apiRequest{resource: "serviceinstances", verb: "list"}
+		deleteSI = &apiRequest{resource: "serviceinstances", verb: "delete"}
+	)
+	cscClient := ts.scCli.ServicecatalogV1beta1().ClusterServiceClasses()
+	siClient := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace)
+
+	runner.StartStep(ctx, "probing API server")
+	for i := 0; i < t.cfg.Iterations; i++ {
+		var className string
+		t.measure(listCSC, func() error {
+			list, err := cscClient.List(metav1.ListOptions{})
+			if err == nil && len(list.Items) > 0 {
+				className = list.Items[i%len(list.Items)].Name
+			}
+			return err
+		})
+		if className != "" {
+			t.measure(getCSC, func() error {
+				_, err := cscClient.Get(className, metav1.GetOptions{})
+				return err
+			})
+		}
+
+		name := fmt.Sprintf("api-latency-instance-%d", i)
+		var created bool
+		t.measure(createSI, func() error {
+			_, err := siClient.Create(&scTypes.ServiceInstance{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: scTypes.ServiceInstanceSpec{
+					PlanReference: scTypes.PlanReference{
+						ClusterServiceClassExternalName: apiLatencyClassExternalName,
+						ClusterServicePlanExternalName:  "default",
+					},
+				},
+			})
+			created = err == nil
+			return err
+		})
+		if created {
+			t.measure(getSI, func() error {
+				_, err := siClient.Get(name, metav1.GetOptions{})
+				return err
+			})
+		}
+		t.measure(listSI, func() error {
+			_, err := siClient.List(metav1.ListOptions{})
+			return err
+		})
+		if created {
+			t.measure(deleteSI, func() error {
+				return siClient.Delete(name, &metav1.DeleteOptions{})
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(t.cfg.Interval):
+		}
+	}
+
+	return t.evaluate(ctx, listCSC, getCSC, createSI, getSI, listSI, deleteSI)
+}
+
+// Name returns the name of the stress test
+func (t *APILatencyTest) Name() string {
+	return "Service Catalog API Server Latency"
+}
+
+func (t *APILatencyTest) measure(req *apiRequest, call func() error) {
+	start := time.Now()
+	err := call()
+	elapsed := time.Since(start)
+
+	req.calls++
+	if err != nil {
+		req.errs = append(req.errs, fmt.Sprintf("%s %s: %v", req.verb, req.resource, err))
+		return
+	}
+	req.latencies.Add(elapsed)
+	if t.latency != nil {
+		t.latency.Observe(elapsed.Seconds(), req.resource, req.verb)
+	}
+}
+
+func (t *APILatencyTest) evaluate(ctx context.Context, requests ...*apiRequest) error {
+	var (
+		breaches []string
+		errs     []string
+	)
+	for _, req := range requests {
+		if req.calls == 0 {
+			continue
+		}
+		summary := req.latencies.Summary()
+		errorRate := float64(len(req.errs)) / float64(req.calls)
+		report := fmt.Sprintf("%s, errors: %d/%d (%.1f%%)", summary, len(req.errs), req.calls, errorRate*100)
+		name := fmt.Sprintf("%s %s", req.verb, req.resource)
+
+		runner.RecordDetail(ctx, name, report)
+		t.log.WithField("ID", runner.RunID(ctx)).Infof("API server latency of %s: %s", name, report)
+
+		if t.cfg.MaxErrorRate > 0 && errorRate > t.cfg.MaxErrorRate {
+			breaches = append(breaches, fmt.Sprintf("%s error rate %.1f%% exceeds %.1f%%", name, errorRate*100, t.cfg.MaxErrorRate*100))
+		}
+		if t.cfg.MaxP90 > 0 && summary.P90 > t.cfg.MaxP90 {
+			breaches = append(breaches, fmt.Sprintf("%s p90 %v exceeds %v", name, summary.P90, t.cfg.MaxP90))
+		}
+		if t.cfg.MaxP99 > 0 && summary.P99 > t.cfg.MaxP99 {
+			breaches = append(breaches, fmt.Sprintf("%s p99 %v exceeds %v", name, summary.P99, t.cfg.MaxP99))
+		}
+		errs = append(errs, req.errs...)
+	}
+	if len(breaches) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("API server latency thresholds breached: %v", breaches)
+	return errors.New(withReportedErrs(msg, errs))
+}
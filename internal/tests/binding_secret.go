@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BindingSecretConfig defines how the content of the Secret created for the ServiceBinding is verified
+type BindingSecretConfig struct {
+	// ExpectedKeys holds keys which must be present in the Secret for the given class
+	ExpectedKeys SecretKeysByClass `envconfig:"default=redis=HOST|PORT|REDIS_PASSWORD"`
+}
+
+// SecretKeysByClass maps the external name of the class to keys expected in the binding Secret.
+// It is configured in the `class=KEY1|KEY2;other-class=KEY3` format.
+type SecretKeysByClass map[string][]string
+
+// Unmarshal parses the SecretKeysByClass from the environment variable
+func (m *SecretKeysByClass) Unmarshal(s string) error {
+	parsed := SecretKeysByClass{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid entry %q, expected format is class=KEY1|KEY2", entry)
+		}
+		parsed[parts[0]] = strings.Split(parts[1], "|")
+	}
+
+	*m = parsed
+	return nil
+}
+
+// assertBindingSecret checks that the Secret created for the ServiceBinding contains keys expected for given class
+// and has no empty values. The reachability of the service endpoint is checked by the service probe.
+func (ts *testSuite) assertBindingSecret(cfg BindingSecretConfig, classExternalName string) error {
+	secret, err := ts.bindingSecret()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, key := range cfg.ExpectedKeys[classExternalName] {
+		if _, found := secret.Data[key]; !found {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		var got []string
+		for k := range secret.Data {
+			got = append(got, k)
+		}
+		sort.Strings(got)
+		return fmt.Errorf("Secret %s/%s misses keys %v expected for class %q, got keys: %v", secret.Namespace, secret.Name, missing, classExternalName, got)
+	}
+
+	return nil
+}
+
+// bindingSecret returns the Secret created for the ServiceBinding and checks that it has no empty values
+func (ts *testSuite) bindingSecret() (*k8sCoreTypes.Secret, error) {
+	b, err := ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace).Get(ts.bindingName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "while getting ServiceBinding")
+	}
+	secretName := b.Spec.SecretName
+	if secretName == "" {
+		secretName = b.Name
+	}
+
+	secret, err := ts.k8sCli.CoreV1().Secrets(ts.namespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while getting Secret %s", secretName)
+	}
+	if len(secret.Data) == 0 {
+		return nil, fmt.Errorf("Secret %s is empty", secretName)
+	}
+	for k, v := range secret.Data {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("Secret %s has empty value under the key %s", secretName, k)
+		}
+	}
+
+	return secret, nil
+}
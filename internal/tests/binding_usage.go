@@ -0,0 +1,246 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	bucTypes "github.com/kyma-project/kyma/components/binding-usage-controller/pkg/apis/servicecatalog/v1alpha1"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	appsV1Types "k8s.io/api/apps/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	restclient "k8s.io/client-go/rest"
+)
+
+// Resources selected by the custom UsageKind
+const (
+	// UsageKindTargetDeployment selects the tester Deployment
+	UsageKindTargetDeployment = "deployment"
+	// UsageKindTargetStatefulSet replaces the tester Deployment with the StatefulSet and selects it
+	UsageKindTargetStatefulSet = "statefulset"
+)
+
+// usageKindTargets holds resources referenced by the custom UsageKind for each supported target
+var usageKindTargets = map[string]bucTypes.ResourceReference{
+	UsageKindTargetDeployment:  {Group: "apps", Kind: "deployment", Version: "v1"},
+	UsageKindTargetStatefulSet: {Group: "apps", Kind: "statefulset", Version: "v1"},
+}
+
+// BindingUsageTest tests the ServiceBindingUsage injection lifecycle:
+// - Injecting the ServiceBinding Secret with the env prefix into the Deployment
+// - Removing injected envs after the ServiceBindingUsage is deleted
+// - Injecting by the custom UsageKind, which selects the Deployment or the StatefulSet by labels
+//
+// Prerequisite:
+// - Helm-Broker is registered in Service Catalog
+// - and ClusterServiceClass `redis` is available with plan `micro`
+// - BindingUsageController is installed
+type BindingUsageTest struct {
+	k8sClientCfg *restclient.Config
+	cfg          BindingUsageTestConfig
+	tester       TesterConfig
+	secretAccess SecretAccessConfig
+	diagnostics  DiagnosticsCollector
+}
+
+// BindingUsageTestConfig holds possible configuration for test
+type BindingUsageTestConfig struct {
+	Enabled   bool   `envconfig:"default=false"`
+	EnvPrefix string `envconfig:"default=SBU_TEST_"`
+	// CustomUsageKind defines if the cluster-wide UsageKind is created for the test run
+	CustomUsageKind bool `envconfig:"default=true"`
+	// UsageKindTarget defines the resource selected by the custom UsageKind, `deployment` or `statefulset`
+	UsageKindTarget string `envconfig:"default=deployment"`
+	Test            runner.ScheduleConfig
+}
+
+// NewBindingUsageTest returns new instance of BindingUsageTest
+func NewBindingUsageTest(cfg BindingUsageTestConfig, tester TesterConfig, secretAccess SecretAccessConfig, k8sClientCfg *restclient.Config, diagnostics DiagnosticsCollector) (*BindingUsageTest, error) {
+	if _, found := usageKindTargets[cfg.UsageKindTarget]; cfg.CustomUsageKind && !found {
+		return nil, errors.Errorf("unknown UsageKind target %q", cfg.UsageKindTarget)
+	}
+
+	return &BindingUsageTest{
+		k8sClientCfg: k8sClientCfg,
+		cfg:          cfg,
+		tester:       tester,
+		secretAccess: secretAccess,
+		diagnostics:  diagnostics,
+	}, nil
+}
+
+// Execute executes the ServiceBindingUsage test
+func (t *BindingUsageTest) Execute(ctx context.Context) (retErr error) {
+	startTime := time.Now()
+
+	// setup
+	runner.StartStep(ctx, "creating test suite")
+	ts, err := newTestSuite(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating test suite")
+	}
+	ts.tester = t.tester
+	usageKindName := fmt.Sprintf("stress-test-kind-%s", rand.String(5))
+
+	runner.StartStep(ctx, "creating test namespace")
+	if err := ts.createTestNamespace(ctx); err != nil {
+		return errors.Wrap(err, "while creating test namespace")
+	}
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		if retErr != nil {
+			runner.StartStep(ctx, "collecting diagnostics")
+			retErr = collectDiagnostics(ctx, t.diagnostics, ts.namespace, startTime, retErr)
+		}
+
+		if t.cfg.CustomUsageKind {
+			runner.StartStep(ctx, "deleting UsageKind")
+			err := ts.bucCli.ServicecatalogV1alpha1().UsageKinds().Delete(usageKindName, &metav1.DeleteOptions{})
+			if err != nil && !apiErrors.IsNotFound(err) {
+				retErr = appendErr(retErr, errors.Wrapf(err, "while deleting UsageKind %s", usageKindName))
+			}
+		}
+
+		runner.StartStep(ctx, "deleting test namespace")
+		if err := ts.ensureTestNamespaceIsDeleted(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
+		}
+	}()
+
+	runner.StartStep(ctx, "granting access to Secrets")
+	if err := grantSecretAccess(ts.k8sCli, t.secretAccess, ts.namespace); err != nil {
+		return errors.Wrap(err, "while granting access to Secrets")
+	}
+
+	prefixedEnv := t.cfg.EnvPrefix + "PORT"
+	steps := []step{
+		{name: "creating ServiceInstance", fn: ts.createAndWaitForRedisInstance},
+		{name: "creating ServiceBinding", fn: ts.createAndWaitForRedisServiceBinding},
+		{name: "creating tester Deployment", fn: ts.createTesterDeploymentAndService},
+		{name: "creating ServiceBindingUsage with env prefix", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.createBindingUsage(ts.bindingUsageName, "deployment", t.cfg.EnvPrefix)
+		}},
+		{name: "verifying prefixed envs", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.assertInjectedEnvVariable(ctx, prefixedEnv, "6379", 2*timeout)
+		}},
+		{name: "deleting ServiceBindingUsage", fn: ts.deleteBindingUsage(ts.bindingUsageName)},
+		{name: "verifying envs are removed", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.assertEnvVariableRemoved(ctx, prefixedEnv, "6379", 2*timeout)
+		}},
+	}
+
+	if t.cfg.CustomUsageKind {
+		kindEnvPrefix := t.cfg.EnvPrefix + "KIND_"
+		kindEnv := kindEnvPrefix + "PORT"
+		kindUsageName := ts.bindingUsageName + "-custom-kind"
+		if t.cfg.UsageKindTarget == UsageKindTargetStatefulSet {
+			steps = append(steps, step{name: "replacing tester Deployment with StatefulSet", fn: ts.replaceTesterDeploymentWithStatefulSet})
+		}
+		steps = append(steps,
+			step{name: "creating UsageKind", fn: func(ctx context.Context, timeout time.Duration) error {
+				return ts.createUsageKind(ctx, usageKindName, usageKindTargets[t.cfg.UsageKindTarget])
+			}},
+			step{name: "creating ServiceBindingUsage with custom UsageKind", fn: func(ctx context.Context, timeout time.Duration) error {
+				return ts.createBindingUsage(kindUsageName, usageKindName, kindEnvPrefix)
+			}},
+			step{name: "verifying envs injected by custom UsageKind", fn: func(ctx context.Context, timeout time.Duration) error {
+				return ts.assertInjectedEnvVariable(ctx, kindEnv, "6379", 2*timeout)
+			}},
+			step{name: "deleting ServiceBindingUsage with custom UsageKind", fn: ts.deleteBindingUsage(kindUsageName)},
+			step{name: "verifying envs injected by custom UsageKind are removed", fn: func(ctx context.Context, timeout time.Duration) error {
+				return ts.assertEnvVariableRemoved(ctx, kindEnv, "6379", 2*timeout)
+			}},
+		)
+	}
+
+	return executeSteps(ctx, timeoutPerStep, steps...)
+}
+
+// Name returns the name of the stress test
+func (t *BindingUsageTest) Name() string {
+	return "E2E ServiceBindingUsage Variants"
+}
+
+// createUsageKind creates the UsageKind which injects envs into given resources selected by the Pod template labels.
+// The UsageKind is labeled, so it is removed by the janitor when the test does not delete it.
+func (ts *testSuite) createUsageKind(ctx context.Context, name string, resource bucTypes.ResourceReference) error {
+	_, err := ts.bucCli.ServicecatalogV1alpha1().UsageKinds().Create(&bucTypes.UsageKind{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "UsageKind",
+			APIVersion: "servicecatalog.kyma-project.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				labels.UsageKind: "true",
+				labels.RunID:     runner.RunID(ctx),
+				labels.CreatedAt: strconv.FormatInt(time.Now().Unix(), 10),
+			},
+		},
+		Spec: bucTypes.UsageKindSpec{
+			DisplayName: fmt.Sprintf("Service Catalog tester %s", resource.Kind),
+			Resource:    &resource,
+			LabelsPath:  "spec.template.metadata.labels",
+		},
+	})
+	return err
+}
+
+// replaceTesterDeploymentWithStatefulSet deletes the tester Deployment and creates the StatefulSet with the same name
+// and Pod template, so the tester Service selects only the StatefulSet Pod
+func (ts *testSuite) replaceTesterDeploymentWithStatefulSet(ctx context.Context, timeout time.Duration) error {
+	podLabels := map[string]string{
+		"app": ts.testerDeploymentName,
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	err := ts.k8sCli.AppsV1beta1().Deployments(ts.namespace).Delete(ts.testerDeploymentName, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		return errors.Wrap(err, "while deleting tester Deployment")
+	}
+
+	err = repeatUntilTimeout(ctx, func() error {
+		pods, err := ts.k8sCli.CoreV1().Pods(ts.namespace).List(metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("app=%s", ts.testerDeploymentName),
+		})
+		if err != nil {
+			return err
+		}
+		if len(pods.Items) > 0 {
+			return fmt.Errorf("%d Pods of the tester Deployment are not removed", len(pods.Items))
+		}
+		return nil
+	}, timeout)
+	if err != nil {
+		return err
+	}
+
+	deploy := ts.envTesterDeployment(podLabels)
+	_, err = ts.k8sCli.AppsV1().StatefulSets(ts.namespace).Create(&appsV1Types.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ts.testerDeploymentName,
+		},
+		Spec: appsV1Types.StatefulSetSpec{
+			ServiceName: ts.testerDeploymentSvcName,
+			Selector:    deploy.Spec.Selector,
+			Replicas:    deploy.Spec.Replicas,
+			Template:    deploy.Spec.Template,
+		},
+	})
+	return errors.Wrap(err, "while creating tester StatefulSet")
+}
+
+func (ts *testSuite) deleteBindingUsage(name string) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		return ts.bucCli.ServicecatalogV1alpha1().ServiceBindingUsages(ts.namespace).Delete(name, &metav1.DeleteOptions{})
+	}
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"fmt"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/pkg/errors"
+)
+
+// instanceReady returns true when the ServiceInstance is ready
+// and error when the Service Catalog gave up provisioning it.
+func instanceReady(si *scTypes.ServiceInstance) (bool, error) {
+	ready := false
+	for _, cond := range si.Status.Conditions {
+		switch {
+		case cond.Type == scTypes.ServiceInstanceConditionFailed && cond.Status == scTypes.ConditionTrue:
+			return false, fmt.Errorf("ServiceInstance %s/%s failed: %s: %s", si.Namespace, si.Name, cond.Reason, cond.Message)
+		case cond.Type == scTypes.ServiceInstanceConditionReady:
+			ready = cond.Status == scTypes.ConditionTrue
+		}
+	}
+	return ready, nil
+}
+
+// bindingReady returns true when the ServiceBinding is ready
+// and error when the Service Catalog gave up creating it.
+func bindingReady(b *scTypes.ServiceBinding) (bool, error) {
+	ready := false
+	for _, cond := range b.Status.Conditions {
+		switch {
+		case cond.Type == scTypes.ServiceBindingConditionFailed && cond.Status == scTypes.ConditionTrue:
+			return false, fmt.Errorf("ServiceBinding %s/%s failed: %s: %s", b.Namespace, b.Name, cond.Reason, cond.Message)
+		case cond.Type == scTypes.ServiceBindingConditionReady:
+			ready = cond.Status == scTypes.ConditionTrue
+		}
+	}
+	return ready, nil
+}
+
+// ensureReady converts the result of instanceReady or bindingReady into the error expected by repeatUntilTimeout
+func ensureReady(ready bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if !ready {
+		return errors.New("resource is not ready")
+	}
+	return nil
+}
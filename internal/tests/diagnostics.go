@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+)
+
+// DiagnosticsCollector collects the diagnostic bundle for the failed test run
+type DiagnosticsCollector interface {
+	Enabled() bool
+	Collect(runID, namespace string, since time.Time) (string, error)
+}
+
+// collectDiagnostics collects the diagnostic bundle before the test namespace is removed
+// and appends its location to the test error, so it is available in the notification after the cause of the failure.
+// The runner waits for the test also after it timed out, so the location is reported also in that case.
+// The test error is returned unchanged when the collector is not set or disabled.
+func collectDiagnostics(ctx context.Context, collector DiagnosticsCollector, namespace string, since time.Time, testErr error) error {
+	if collector == nil || !collector.Enabled() {
+		return testErr
+	}
+
+	location, err := collector.Collect(runner.RunID(ctx), namespace, since)
+	if err != nil {
+		return errors.Errorf("%v\ncannot collect diagnostic bundle: %v", testErr, err)
+	}
+	return errors.Errorf("%v\ndiagnostic bundle: %s", testErr, location)
+}
@@ -1,8 +1,8 @@
 package tests
 
 import (
+	"context"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
@@ -12,10 +12,11 @@ import (
 	bucTypes "github.com/kyma-project/kyma/components/binding-usage-controller/pkg/apis/servicecatalog/v1alpha1"
 	bucClient "github.com/kyma-project/kyma/components/binding-usage-controller/pkg/client/clientset/versioned"
 
+	"github.com/kyma-incubator/service-catalog-tester/internal/probe"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
 	"github.com/pkg/errors"
 	appsTypes "k8s.io/api/apps/v1beta1"
 	k8sCoreTypes "k8s.io/api/core/v1"
-	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/rand"
@@ -28,6 +29,9 @@ const timeoutPerStep = time.Minute
 // E2EServiceCatalogHappyPathTest tests the Service Catalog basic functionality:
 // - Creating ServiceInstance
 // - Create ServiceBininding
+// - Measuring phases of the ServiceInstance and ServiceBinding reconciliation
+// - Verifying the content of the ServiceBinding Secret
+// - Talking to the provisioned service with the ServiceBinding credentials
 // - and injecting those bindings to sample application
 //
 // Prerequisite:
@@ -37,60 +41,109 @@ const timeoutPerStep = time.Minute
 type E2EServiceCatalogHappyPathTest struct {
 	k8sClientCfg           *restclient.Config
 	testOnlyServiceCatalog bool
+	bindingSecretCfg       BindingSecretConfig
+	secretAccess           SecretAccessConfig
+	serviceProbe           probe.Probe
+	tester                 TesterConfig
+	reconciliationLatency  LatencyObserver
+	diagnostics            DiagnosticsCollector
 }
 
 // E2EServiceCatalogHappyPathTestConfig holds possible configuration for test
 type E2EServiceCatalogHappyPathTestConfig struct {
-	TestOnlyServiceCatalog bool          `envconfig:"default=false"`
-	TestThrottle           time.Duration `envconfig:"default=60s"`
+	TestOnlyServiceCatalog bool `envconfig:"default=false"`
+	Test                   runner.ScheduleConfig
 }
 
 // NewE2EServiceCatalogHappyPathTest returns new instance of E2EServiceCatalogHappyPathTest
-func NewE2EServiceCatalogHappyPathTest(cfg E2EServiceCatalogHappyPathTestConfig, k8sClientCfg *restclient.Config) *E2EServiceCatalogHappyPathTest {
+func NewE2EServiceCatalogHappyPathTest(cfg E2EServiceCatalogHappyPathTestConfig, bindingSecretCfg BindingSecretConfig, secretAccess SecretAccessConfig, serviceProbe probe.Probe, tester TesterConfig, reconciliationLatency LatencyObserver, k8sClientCfg *restclient.Config, diagnostics DiagnosticsCollector) *E2EServiceCatalogHappyPathTest {
 	return &E2EServiceCatalogHappyPathTest{
 		k8sClientCfg:           k8sClientCfg,
 		testOnlyServiceCatalog: cfg.TestOnlyServiceCatalog,
+		bindingSecretCfg:       bindingSecretCfg,
+		secretAccess:           secretAccess,
+		serviceProbe:           serviceProbe,
+		tester:                 tester,
+		reconciliationLatency:  reconciliationLatency,
+		diagnostics:            diagnostics,
 	}
 }
 
 // Execute executes basic Service Catalog test
-func (t *E2EServiceCatalogHappyPathTest) Execute(stop <-chan struct{}) (retErr error) {
+func (t *E2EServiceCatalogHappyPathTest) Execute(ctx context.Context) (retErr error) {
+	startTime := time.Now()
+
 	// setup
-	ts, err := t.newTestSuite()
+	runner.StartStep(ctx, "creating test suite")
+	ts, err := newTestSuite(t.k8sClientCfg)
 	if err != nil {
 		return errors.Wrap(err, "while creating test suite")
 	}
+	ts.tester = t.tester
 
-	if err := ts.createTestNamespace(); err != nil {
+	runner.StartStep(ctx, "creating test namespace")
+	if err := ts.createTestNamespace(ctx); err != nil {
 		return errors.Wrap(err, "while creating test namespace")
 	}
 	// clean-up
 	defer func() {
-		if err := ts.ensureTestNamespaceIsDeleted(stop); err != nil {
-			retErr = t.appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		if retErr != nil {
+			runner.StartStep(ctx, "collecting diagnostics")
+			retErr = collectDiagnostics(ctx, t.diagnostics, ts.namespace, startTime, retErr)
+		}
+
+		runner.StartStep(ctx, "deleting test namespace")
+		if err := ts.ensureTestNamespaceIsDeleted(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
 		}
 	}()
 
+	runner.StartStep(ctx, "granting access to Secrets")
+	if err := grantSecretAccess(ts.k8sCli, t.secretAccess, ts.namespace); err != nil {
+		return errors.Wrap(err, "while granting access to Secrets")
+	}
+
+	runner.StartStep(ctx, "watching ServiceInstances and ServiceBindings")
+	ts.reconciliation, err = ts.startReconciliationRecorder()
+	if err != nil {
+		return errors.Wrap(err, "while starting reconciliation recorder")
+	}
+	defer func() {
+		ts.reconciliation.stop()
+		ts.reconciliation.report(ctx, t.reconciliationLatency, kindServiceInstance, ts.serviceInstanceName)
+		ts.reconciliation.report(ctx, t.reconciliationLatency, kindServiceBinding, ts.bindingName)
+	}()
+
 	// e2e creation steps
-	steps := []func(timeout time.Duration) error{
-		ts.createAndWaitForRedisInstance,
-		ts.createAndWaitForRedisServiceBinding,
+	steps := []step{
+		{name: "creating ServiceInstance", fn: ts.createAndWaitForRedisInstance},
+		{name: "creating ServiceBinding", fn: ts.createAndWaitForRedisServiceBinding},
+		{name: "verifying ServiceBinding Secret", fn: func(context.Context, time.Duration) error {
+			return ts.assertBindingSecret(t.bindingSecretCfg, "redis")
+		}},
+	}
+	if t.serviceProbe != nil {
+		steps = append(steps, step{name: "probing service", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.probeService(ctx, t.serviceProbe, timeout)
+		}})
 	}
 	if !t.testOnlyServiceCatalog {
 		steps = append(steps,
-			ts.createTesterDeploymentAndService,
-			ts.createBindingUsageForTesterDeployment)
+			step{name: "creating tester Deployment", fn: ts.createTesterDeploymentAndService},
+			step{name: "creating ServiceBindingUsage", fn: ts.createBindingUsageForTesterDeployment})
 	}
 
-	for _, step := range steps {
-		if err := step(timeoutPerStep); err != nil {
-			return err
-		}
+	if err := executeSteps(ctx, timeoutPerStep, steps...); err != nil {
+		return err
 	}
 
 	if !t.testOnlyServiceCatalog {
 		// verification
-		if err := ts.assertInjectedEnvVariable("PORT", "6379", 2*timeoutPerStep); err != nil {
+		runner.StartStep(ctx, "verifying injected envs")
+		if err := ts.assertInjectedEnvVariable(ctx, "PORT", "6379", 2*timeoutPerStep); err != nil {
 			return errors.Wrap(err, "while checking that envs are injected")
 		}
 	}
@@ -103,20 +156,20 @@ func (t *E2EServiceCatalogHappyPathTest) Name() string {
 	return "E2E ServiceCatalog Happy Path"
 }
 
-func (t *E2EServiceCatalogHappyPathTest) newTestSuite() (*testSuite, error) {
+func newTestSuite(k8sClientCfg *restclient.Config) (*testSuite, error) {
 	randID := rand.String(5)
 
-	k8sCli, err := kubernetes.NewForConfig(t.k8sClientCfg)
+	k8sCli, err := kubernetes.NewForConfig(k8sClientCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	scCli, err := scClient.NewForConfig(t.k8sClientCfg)
+	scCli, err := scClient.NewForConfig(k8sClientCfg)
 	if err != nil {
 		return nil, err
 	}
 
-	bucCli, err := bucClient.NewForConfig(t.k8sClientCfg)
+	bucCli, err := bucClient.NewForConfig(k8sClientCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -129,9 +182,11 @@ func (t *E2EServiceCatalogHappyPathTest) newTestSuite() (*testSuite, error) {
 		namespace:            fmt.Sprintf("stress-test-%s", randID),
 		testerDeploymentName: fmt.Sprintf("stress-test-env-tester-%s", randID),
 
+		brokerName:              fmt.Sprintf("stress-test-broker-%s", randID),
 		serviceInstanceName:     fmt.Sprintf("stress-test-instance-a-%s", randID),
 		bindingName:             fmt.Sprintf("stress-test-credential-a-%s", randID),
 		testerDeploymentSvcName: fmt.Sprintf("stress-test-svc-id-a-%s", randID),
+		bindingUsageName:        "binding-usage-tester",
 	}, nil
 }
 
@@ -144,20 +199,24 @@ type testSuite struct {
 
 	testerDeploymentName string
 
+	brokerName              string
 	serviceInstanceName     string
 	bindingName             string
 	testerDeploymentSvcName string
+	bindingUsageName        string
+
+	tester TesterConfig
+	// reconciliation records the reconciliation timeline of created resources, when set
+	reconciliation *reconciliationRecorder
 }
 
 // K8s namespace helpers
-func (ts *testSuite) createTestNamespace() error {
+func (ts *testSuite) createTestNamespace(ctx context.Context) error {
 	nsClient := ts.k8sCli.CoreV1().Namespaces()
 	_, err := nsClient.Create(&k8sCoreTypes.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: ts.namespace,
-			Labels: map[string]string{
-				"env": "true",
-			},
+			Name:   ts.namespace,
+			Labels: testNamespaceLabels(ctx),
 		},
 	})
 	if err != nil {
@@ -167,48 +226,39 @@ func (ts *testSuite) createTestNamespace() error {
 	return nil
 }
 
-func (ts *testSuite) ensureTestNamespaceIsDeleted(stop <-chan struct{}) error {
-	nsClient := ts.k8sCli.CoreV1().Namespaces()
-	if err := nsClient.Delete(ts.namespace, &metav1.DeleteOptions{}); err != nil {
-		return err
-	}
-
-	for {
-		select {
-		case <-stop:
-			return errors.Errorf("Stop channel closed. Test namespace %s can be still in Terminating phase", ts.namespace)
-		default:
-		}
-
-		_, err := nsClient.Get(ts.namespace, metav1.GetOptions{})
-		if !apiErrors.IsNotFound(err) {
-			continue
-		}
-
-		break
-	}
-
-	return nil
+func (ts *testSuite) ensureTestNamespaceIsDeleted(ctx context.Context) error {
+	return deleteNamespaceAndWait(ctx, ts.k8sCli, ts.scCli, ts.namespace, namespaceDeletionTimeout)
 }
 
 // ServiceInstance helpers
-func (ts *testSuite) createAndWaitForRedisInstance(timeout time.Duration) error {
+func (ts *testSuite) createAndWaitForRedisInstance(ctx context.Context, timeout time.Duration) error {
+	return ts.createAndWaitForInstance(ctx, scTypes.PlanReference{
+		ClusterServiceClassExternalName: "redis",
+		ClusterServicePlanExternalName:  "micro",
+	}, timeout)
+}
+
+func (ts *testSuite) createAndWaitForInstance(ctx context.Context, planRef scTypes.PlanReference, timeout time.Duration) error {
 	siClient := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace)
+	ts.reconciliation.requested(kindServiceInstance, ts.serviceInstanceName)
 	_, err := siClient.Create(&scTypes.ServiceInstance{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: ts.serviceInstanceName,
 		},
 		Spec: scTypes.ServiceInstanceSpec{
-			PlanReference: scTypes.PlanReference{
-				ClusterServiceClassExternalName: "redis",
-				ClusterServicePlanExternalName:  "micro",
-			},
+			PlanReference: planRef,
 		},
 	})
+	ts.reconciliation.admitted(kindServiceInstance, ts.serviceInstanceName)
 	if err != nil {
 		return err
 	}
 
+	return ts.waitForServiceInstanceReady(ctx, timeout)
+}
+
+func (ts *testSuite) waitForServiceInstanceReady(ctx context.Context, timeout time.Duration) error {
+	siClient := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace)
 	instanceInReadyState := func() error {
 		si, err := siClient.Get(ts.serviceInstanceName, metav1.GetOptions{})
 		if err != nil {
@@ -231,16 +281,13 @@ func (ts *testSuite) createAndWaitForRedisInstance(timeout time.Duration) error
 		return nil
 	}
 
-	if err = repeatUntilTimeout(instanceInReadyState, timeout); err != nil {
-		return err
-	}
-
-	return nil
+	return repeatUntilTimeout(ctx, instanceInReadyState, timeout)
 }
 
 // Binding helpers
-func (ts *testSuite) createAndWaitForRedisServiceBinding(timeout time.Duration) error {
+func (ts *testSuite) createAndWaitForRedisServiceBinding(ctx context.Context, timeout time.Duration) error {
 	bindingClient := ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace)
+	ts.reconciliation.requested(kindServiceBinding, ts.bindingName)
 	_, err := bindingClient.Create(&scTypes.ServiceBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ts.bindingName,
@@ -252,11 +299,12 @@ func (ts *testSuite) createAndWaitForRedisServiceBinding(timeout time.Duration)
 			},
 		},
 	})
+	ts.reconciliation.admitted(kindServiceBinding, ts.bindingName)
 	if err != nil {
 		return err
 	}
 
-	err = repeatUntilTimeout(func() error {
+	err = repeatUntilTimeout(ctx, func() error {
 		b, err := bindingClient.Get(ts.bindingName, metav1.GetOptions{})
 		if err != nil {
 			return err
@@ -285,25 +333,36 @@ func (ts *testSuite) createAndWaitForRedisServiceBinding(timeout time.Duration)
 }
 
 // BindingUsage helpers
-func (ts *testSuite) createBindingUsageForTesterDeployment(timeout time.Duration) error {
+func (ts *testSuite) createBindingUsageForTesterDeployment(ctx context.Context, timeout time.Duration) error {
+	return ts.createBindingUsage(ts.bindingUsageName, "deployment", "")
+}
+
+// createBindingUsage creates the ServiceBindingUsage of given kind for the tester Deployment.
+// Injected env variables are prefixed with envPrefix when it is not empty.
+func (ts *testSuite) createBindingUsage(name, usedByKind, envPrefix string) error {
 	sbu := &bucTypes.ServiceBindingUsage{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ServiceBindingUsage",
 			APIVersion: "servicecatalog.kyma-project.io/v1alpha1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "binding-usage-tester",
+			Name: name,
 		},
 		Spec: bucTypes.ServiceBindingUsageSpec{
 			ServiceBindingRef: bucTypes.LocalReferenceByName{
 				Name: ts.bindingName,
 			},
 			UsedBy: bucTypes.LocalReferenceByKindAndName{
-				Kind: "deployment",
+				Kind: usedByKind,
 				Name: ts.testerDeploymentName,
 			},
 		},
 	}
+	if envPrefix != "" {
+		sbu.Spec.Parameters = &bucTypes.Parameters{
+			EnvPrefix: &bucTypes.EnvPrefix{Name: envPrefix},
+		}
+	}
 
 	_, err := ts.bucCli.ServicecatalogV1alpha1().ServiceBindingUsages(ts.namespace).Create(sbu)
 	if err != nil {
@@ -314,7 +373,7 @@ func (ts *testSuite) createBindingUsageForTesterDeployment(timeout time.Duration
 }
 
 // Deployment helpers
-func (ts *testSuite) createTesterDeploymentAndService(timeout time.Duration) error {
+func (ts *testSuite) createTesterDeploymentAndService(ctx context.Context, timeout time.Duration) error {
 	labels := map[string]string{
 		"app": ts.testerDeploymentName,
 	}
@@ -373,7 +432,7 @@ func (ts *testSuite) envTesterDeployment(labels map[string]string) *appsTypes.De
 					Containers: []k8sCoreTypes.Container{
 						{
 							Name:  "app",
-							Image: "eu.gcr.io/kyma-project/acceptance-tests:0.3.319",
+							Image: ts.tester.Image,
 							Ports: []k8sCoreTypes.ContainerPort{
 								{
 									Name:          "http",
@@ -381,7 +440,7 @@ func (ts *testSuite) envTesterDeployment(labels map[string]string) *appsTypes.De
 									ContainerPort: 8080,
 								},
 							},
-							Command: []string{"/go/bin/env-tester.bin"},
+							Command: []string{ts.tester.Command},
 						},
 					},
 				},
@@ -390,39 +449,38 @@ func (ts *testSuite) envTesterDeployment(labels map[string]string) *appsTypes.De
 	}
 }
 
-func (ts *testSuite) assertInjectedEnvVariable(envName string, envValue string, timeout time.Duration) error {
-	req := fmt.Sprintf("http://%s.%s.svc.cluster.local/envs?name=%s&value=%s", ts.testerDeploymentSvcName, ts.namespace, envName, envValue)
-
-	err := repeatUntilTimeout(func() error {
-		resp, err := http.Get(req)
-		if err != nil {
-			return err
-		}
+// step is a single named phase of the test scenario
+type step struct {
+	name string
+	fn   func(ctx context.Context, timeout time.Duration) error
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("while checking if proper env is injected, received unexpected status code [got: %d, expected: %d]", resp.StatusCode, http.StatusOK)
+// executeSteps executes given steps one after another and stops on the first error
+func executeSteps(ctx context.Context, timeoutPerStep time.Duration, steps ...step) error {
+	for _, s := range steps {
+		runner.StartStep(ctx, s.name)
+		if err := s.fn(ctx, timeoutPerStep); err != nil {
+			return errors.Wrapf(err, "while %s", s.name)
 		}
-
-		return nil
-	}, timeout)
-	if err != nil {
-		return err
 	}
 
 	return nil
 }
 
-func repeatUntilTimeout(fn func() error, timeout time.Duration) error {
-	tickCh := time.Tick(time.Second)
+func repeatUntilTimeout(ctx context.Context, fn func() error, timeout time.Duration) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	timeoutCh := time.After(timeout)
 	var lastErr error
 
 waitingLoop:
 	for {
 		select {
+		case <-ctx.Done():
+			return errors.Errorf("Waiting for resource interrupted: %v, last noticed error: %v", ctx.Err(), lastErr)
 		case <-timeoutCh:
 			return errors.Errorf("Waiting for resource failed in given timeout %v, last noticed error: %v", timeout, lastErr)
-		case <-tickCh:
+		case <-ticker.C:
 			if err := fn(); err != nil {
 				lastErr = err
 				continue
@@ -434,7 +492,7 @@ waitingLoop:
 	return nil
 }
 
-func (t *E2EServiceCatalogHappyPathTest) appendErr(err error, errToAppend ...error) error {
+func appendErr(err error, errToAppend ...error) error {
 	var msg []string
 	for _, e := range errToAppend {
 		msg = append(msg, e.Error())
@@ -450,3 +508,19 @@ func (t *E2EServiceCatalogHappyPathTest) appendErr(err error, errToAppend ...err
 
 	return errors.New(strings.Join(msg, ";"))
 }
+
+// maxReportedErrs is the number of errors listed in the message of the test which failed its thresholds
+const maxReportedErrs = 5
+
+// withReportedErrs appends at most maxReportedErrs errors to the message, each in the new line,
+// and the number of the omitted ones
+func withReportedErrs(msg string, errs []string) string {
+	for i, e := range errs {
+		if i == maxReportedErrs {
+			msg += fmt.Sprintf("\n... and %d more errors", len(errs)-maxReportedErrs)
+			break
+		}
+		msg += "\n" + e
+	}
+	return msg
+}
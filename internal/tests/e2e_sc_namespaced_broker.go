@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	restclient "k8s.io/client-go/rest"
+)
+
+// E2ENamespacedBrokerTest tests the namespace-scoped Service Catalog functionality:
+// - Registering ServiceBroker in the test namespace
+// - Waiting for the ServiceClass and ServicePlan provided by that broker
+// - Creating ServiceInstance by the ServiceClassExternalName
+// - Creating ServiceBinding
+//
+// Prerequisite:
+//   - Service Catalog has NamespacedServiceBroker feature enabled
+//   - broker available under the BrokerURL provides given class and plan
+//     or UseFakeBroker is set to true, so the fake broker is used instead
+type E2ENamespacedBrokerTest struct {
+	k8sClientCfg  *restclient.Config
+	cfg           E2ENamespacedBrokerTestConfig
+	fakeBrokerCfg FakeBrokerConfig
+	diagnostics   DiagnosticsCollector
+}
+
+// E2ENamespacedBrokerTestConfig holds possible configuration for test
+type E2ENamespacedBrokerTestConfig struct {
+	Enabled                  bool   `envconfig:"default=false"`
+	BrokerURL                string `envconfig:"default=http://helm-broker.kyma-system.svc.cluster.local"`
+	ServiceClassExternalName string `envconfig:"default=redis"`
+	ServicePlanExternalName  string `envconfig:"default=micro"`
+	// UseFakeBroker defines if the fake broker is registered instead of the one available under the BrokerURL.
+	// The fake broker service and plan are used in that case.
+	UseFakeBroker bool `envconfig:"default=false"`
+	Test          runner.ScheduleConfig
+}
+
+// NewE2ENamespacedBrokerTest returns new instance of E2ENamespacedBrokerTest
+func NewE2ENamespacedBrokerTest(cfg E2ENamespacedBrokerTestConfig, fakeBrokerCfg FakeBrokerConfig, k8sClientCfg *restclient.Config, diagnostics DiagnosticsCollector) *E2ENamespacedBrokerTest {
+	if cfg.UseFakeBroker {
+		cfg.ServiceClassExternalName = fakebroker.ServiceName
+		cfg.ServicePlanExternalName = fakebroker.PlanMicro
+	}
+
+	return &E2ENamespacedBrokerTest{
+		k8sClientCfg:  k8sClientCfg,
+		cfg:           cfg,
+		fakeBrokerCfg: fakeBrokerCfg,
+		diagnostics:   diagnostics,
+	}
+}
+
+// Execute executes namespaced Service Catalog test
+func (t *E2ENamespacedBrokerTest) Execute(ctx context.Context) (retErr error) {
+	startTime := time.Now()
+
+	// setup
+	runner.StartStep(ctx, "creating test suite")
+	ts, err := newTestSuite(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating test suite")
+	}
+
+	runner.StartStep(ctx, "creating test namespace")
+	if err := ts.createTestNamespace(ctx); err != nil {
+		return errors.Wrap(err, "while creating test namespace")
+	}
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		if retErr != nil {
+			runner.StartStep(ctx, "collecting diagnostics")
+			retErr = collectDiagnostics(ctx, t.diagnostics, ts.namespace, startTime, retErr)
+		}
+
+		runner.StartStep(ctx, "deleting test namespace")
+		if err := ts.ensureTestNamespaceIsDeleted(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
+		}
+	}()
+
+	brokerURL := t.cfg.BrokerURL
+	var steps []step
+	if t.cfg.UseFakeBroker {
+		steps = append(steps, step{name: "deploying fake broker", fn: func(ctx context.Context, timeout time.Duration) error {
+			url, err := ts.deployFakeBroker(ctx, t.fakeBrokerCfg, timeout)
+			brokerURL = url
+			return err
+		}})
+	}
+
+	steps = append(steps,
+		step{name: "registering ServiceBroker", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.registerAndWaitForServiceBroker(ctx, brokerURL, timeout)
+		}},
+		step{name: "waiting for ServiceClass and ServicePlan", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.waitForServiceClassAndPlan(ctx, t.cfg.ServiceClassExternalName, t.cfg.ServicePlanExternalName, timeout)
+		}},
+		step{name: "creating ServiceInstance", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.createAndWaitForNamespacedInstance(ctx, t.cfg.ServiceClassExternalName, t.cfg.ServicePlanExternalName, timeout)
+		}},
+		step{name: "creating ServiceBinding", fn: ts.createAndWaitForRedisServiceBinding},
+	)
+
+	return executeSteps(ctx, timeoutPerStep, steps...)
+}
+
+// Name returns the name of the stress test
+func (t *E2ENamespacedBrokerTest) Name() string {
+	return "E2E ServiceCatalog Namespaced Broker"
+}
+
+// ServiceBroker helpers
+func (ts *testSuite) registerAndWaitForServiceBroker(ctx context.Context, url string, timeout time.Duration) error {
+	brokerClient := ts.scCli.ServicecatalogV1beta1().ServiceBrokers(ts.namespace)
+	_, err := brokerClient.Create(&scTypes.ServiceBroker{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ts.brokerName,
+		},
+		Spec: scTypes.ServiceBrokerSpec{
+			CommonServiceBrokerSpec: scTypes.CommonServiceBrokerSpec{
+				URL: url,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return repeatUntilTimeout(ctx, func() error {
+		b, err := brokerClient.Get(ts.brokerName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, cond := range b.Status.Conditions {
+			if cond.Type == scTypes.ServiceBrokerConditionReady && cond.Status == scTypes.ConditionTrue {
+				return nil
+			}
+		}
+		return fmt.Errorf("ServiceBroker %s/%s is not in ready state. Status: %+v", b.Namespace, b.Name, b.Status)
+	}, timeout)
+}
+
+func (ts *testSuite) waitForServiceClassAndPlan(ctx context.Context, classExternalName, planExternalName string, timeout time.Duration) error {
+	scCli := ts.scCli.ServicecatalogV1beta1()
+
+	return repeatUntilTimeout(ctx, func() error {
+		classes, err := scCli.ServiceClasses(ts.namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		var className string
+		for _, c := range classes.Items {
+			if c.Spec.ServiceBrokerName == ts.brokerName && c.Spec.ExternalName == classExternalName {
+				className = c.Name
+				break
+			}
+		}
+		if className == "" {
+			return fmt.Errorf("ServiceClass %q from ServiceBroker %s/%s not found", classExternalName, ts.namespace, ts.brokerName)
+		}
+
+		plans, err := scCli.ServicePlans(ts.namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range plans.Items {
+			if p.Spec.ServiceClassRef.Name == className && p.Spec.ExternalName == planExternalName {
+				return nil
+			}
+		}
+		return fmt.Errorf("ServicePlan %q of ServiceClass %q from ServiceBroker %s/%s not found", planExternalName, classExternalName, ts.namespace, ts.brokerName)
+	}, timeout)
+}
+
+// ServiceInstance helpers
+func (ts *testSuite) createAndWaitForNamespacedInstance(ctx context.Context, classExternalName, planExternalName string, timeout time.Duration) error {
+	return ts.createAndWaitForInstance(ctx, scTypes.PlanReference{
+		ServiceClassExternalName: classExternalName,
+		ServicePlanExternalName:  planExternalName,
+	}, timeout)
+}
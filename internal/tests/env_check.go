@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilNet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// EnvCheckMode defines how envs injected into the tester Deployment are verified
+type EnvCheckMode string
+
+// Supported modes of the env check
+const (
+	// EnvCheckService calls the tester through the cluster DNS, works only in the cluster
+	EnvCheckService EnvCheckMode = "service"
+	// EnvCheckProxy calls the tester through the API server service proxy
+	EnvCheckProxy EnvCheckMode = "proxy"
+	// EnvCheckPodSpec resolves envs from the spec of the tester Pods and referenced Secrets
+	EnvCheckPodSpec EnvCheckMode = "pod-spec"
+)
+
+// Unmarshal parses and validates the EnvCheckMode from the environment variable
+func (m *EnvCheckMode) Unmarshal(s string) error {
+	switch mode := EnvCheckMode(s); mode {
+	case EnvCheckService, EnvCheckProxy, EnvCheckPodSpec:
+		*m = mode
+		return nil
+	default:
+		return fmt.Errorf("unknown env check mode %q, expected one of: %s, %s, %s", s, EnvCheckService, EnvCheckProxy, EnvCheckPodSpec)
+	}
+}
+
+// TesterConfig holds configuration of the workload into which the ServiceBinding is injected
+type TesterConfig struct {
+	Image    string       `envconfig:"default=eu.gcr.io/kyma-project/acceptance-tests:0.3.319"`
+	Command  string       `envconfig:"default=/go/bin/env-tester.bin"`
+	EnvCheck EnvCheckMode `envconfig:"default=service"`
+}
+
+// assertInjectedEnvVariable waits until the tester has the env with given value
+func (ts *testSuite) assertInjectedEnvVariable(ctx context.Context, envName string, envValue string, timeout time.Duration) error {
+	return repeatUntilTimeout(ctx, func() error {
+		injected, err := ts.envInjected(ctx, envName, envValue)
+		if err != nil {
+			return err
+		}
+		if !injected {
+			return fmt.Errorf("env %s=%s is not injected", envName, envValue)
+		}
+		return nil
+	}, timeout)
+}
+
+// assertEnvVariableRemoved waits until the tester reports that the env has not the value injected before
+func (ts *testSuite) assertEnvVariableRemoved(ctx context.Context, envName string, envValue string, timeout time.Duration) error {
+	return repeatUntilTimeout(ctx, func() error {
+		injected, err := ts.envInjected(ctx, envName, envValue)
+		if err != nil {
+			return err
+		}
+		if injected {
+			return fmt.Errorf("env %s is still injected", envName)
+		}
+		return nil
+	}, timeout)
+}
+
+// envInjected returns true when the tester has the env with given value and false when it has not.
+// The error is returned when it cannot be determined, e.g. during the tester rollout.
+func (ts *testSuite) envInjected(ctx context.Context, envName string, envValue string) (bool, error) {
+	switch ts.tester.EnvCheck {
+	case EnvCheckProxy:
+		return ts.envInjectedByProxy(envName, envValue)
+	case EnvCheckPodSpec:
+		return ts.envInjectedInPodSpec(envName, envValue)
+	default:
+		return ts.envInjectedByService(ctx, envName, envValue)
+	}
+}
+
+func (ts *testSuite) envInjectedByService(ctx context.Context, envName string, envValue string) (bool, error) {
+	url := fmt.Sprintf("http://%s.%s.svc.cluster.local/envs?name=%s&value=%s", ts.testerDeploymentSvcName, ts.namespace, envName, envValue)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	return envCheckResult(resp.StatusCode)
+}
+
+// envInjectedByProxy interprets only the status code returned by the tester. Errors reported by the API server itself,
+// e.g. when the access is forbidden, the Service is not found or has no endpoints, are returned as errors.
+func (ts *testSuite) envInjectedByProxy(envName string, envValue string) (bool, error) {
+	var statusCode int
+	// the request is built like by the ProxyGet method, which does not expose the status code of the response
+	body, err := ts.k8sCli.CoreV1().RESTClient().Get().
+		Namespace(ts.namespace).
+		Resource("services").
+		SubResource("proxy").
+		Name(utilNet.JoinSchemeNamePort("http", ts.testerDeploymentSvcName, "http")).
+		Suffix("envs").
+		Param("name", envName).
+		Param("value", envValue).
+		Do().
+		StatusCode(&statusCode).
+		Raw()
+	if err != nil && (statusCode == 0 || apiServerStatus(body)) {
+		return false, errors.Wrap(err, "while calling tester through the API server proxy")
+	}
+	return envCheckResult(statusCode)
+}
+
+// apiServerStatus returns true when the body is the Status object returned by the API server instead of the tester response
+func apiServerStatus(body []byte) bool {
+	var status metav1.Status
+	return json.Unmarshal(body, &status) == nil && status.Kind == "Status"
+}
+
+// envCheckResult interprets the status code returned by the tester
+func envCheckResult(statusCode int) (bool, error) {
+	switch {
+	case statusCode == http.StatusOK:
+		return true, nil
+	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError:
+		return false, nil
+	default:
+		return false, fmt.Errorf("while checking if proper env is injected, received unexpected status code %d", statusCode)
+	}
+}
+
+// envInjectedInPodSpec checks envs of all running tester Pods. Envs from Secrets referenced by `envFrom`
+// are resolved, as the ServiceBindingUsage injects them that way.
+func (ts *testSuite) envInjectedInPodSpec(envName string, envValue string) (bool, error) {
+	selector := labels.SelectorFromSet(map[string]string{"app": ts.testerDeploymentName})
+	pods, err := ts.k8sCli.CoreV1().Pods(ts.namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, errors.Wrap(err, "while listing tester Pods")
+	}
+
+	var withEnv, withoutEnv int
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != k8sCoreTypes.PodRunning {
+			continue
+		}
+
+		found, err := ts.podHasEnv(pod, envName, envValue)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			withEnv++
+		} else {
+			withoutEnv++
+		}
+	}
+
+	switch {
+	case withEnv == 0 && withoutEnv == 0:
+		return false, errors.New("no running tester Pods")
+	case withEnv > 0 && withoutEnv > 0:
+		return false, fmt.Errorf("env %s is injected only into %d of %d running tester Pods", envName, withEnv, withEnv+withoutEnv)
+	default:
+		return withEnv > 0, nil
+	}
+}
+
+func (ts *testSuite) podHasEnv(pod k8sCoreTypes.Pod, envName string, envValue string) (bool, error) {
+	for _, c := range pod.Spec.Containers {
+		for _, env := range c.Env {
+			if env.Name == envName && env.Value == envValue {
+				return true, nil
+			}
+		}
+
+		for _, from := range c.EnvFrom {
+			if from.SecretRef == nil || !strings.HasPrefix(envName, from.Prefix) {
+				continue
+			}
+			secret, err := ts.k8sCli.CoreV1().Secrets(ts.namespace).Get(from.SecretRef.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, errors.Wrapf(err, "while getting Secret %s referenced by Pod %s", from.SecretRef.Name, pod.Name)
+			}
+			if value, found := secret.Data[strings.TrimPrefix(envName, from.Prefix)]; found && string(value) == envValue {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
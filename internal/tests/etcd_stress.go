@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/etcd"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/stats"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+// etcdKeyPrefix is the prefix of all keys written by the test, so they never collide with the Service Catalog data
+const etcdKeyPrefix = "/service-catalog-tester/"
+
+// EtcdStressTest populates keys in the Service Catalog etcd and measures the read and write latency:
+// - TLS client certificate is read from the Secret
+// - keys are written and read by concurrent workers
+// - the test fails when the latency percentiles exceed thresholds or when any request fails
+// - reading the first written revision at the end detects if compaction happened during the test
+// - written keys are removed at the end of the test
+type EtcdStressTest struct {
+	cfg          EtcdStressTestConfig
+	k8sClientCfg *restclient.Config
+	log          logrus.FieldLogger
+}
+
+// EtcdStressTestConfig holds possible configuration for test
+type EtcdStressTestConfig struct {
+	Enabled   bool   `envconfig:"default=false"`
+	Endpoint  string `envconfig:"default=https://service-catalog-etcd-client.kyma-system.svc.cluster.local:2379"`
+	APIPrefix string `envconfig:"default=/v3beta"`
+	// TLS material is read from the Secret. TLS is not used if SecretName is empty.
+	SecretNamespace    string `envconfig:"default=kyma-system"`
+	SecretName         string `envconfig:"optional"`
+	SecretCertKey      string `envconfig:"default=etcd-client.crt"`
+	SecretKeyKey       string `envconfig:"default=etcd-client.key"`
+	SecretCAKey        string `envconfig:"default=etcd-client-ca.crt"`
+	InsecureSkipVerify bool   `envconfig:"default=false"`
+
+	KeyCount       int           `envconfig:"default=100"`
+	KeySize        int           `envconfig:"default=1000"`
+	Concurrency    int           `envconfig:"default=1"`
+	RequestTimeout time.Duration `envconfig:"default=10s"`
+	// MaxWriteP99 and MaxReadP99 are the latency thresholds. Zero disables the threshold.
+	MaxWriteP99 time.Duration `envconfig:"default=1s"`
+	MaxReadP99  time.Duration `envconfig:"default=500ms"`
+	Test        runner.ScheduleConfig
+}
+
+// NewEtcdStressTest returns new instance of EtcdStressTest
+func NewEtcdStressTest(cfg EtcdStressTestConfig, k8sClientCfg *restclient.Config, log logrus.FieldLogger) (*EtcdStressTest, error) {
+	if cfg.KeyCount < 1 {
+		return nil, errors.Errorf("key count must be at least 1, got %d", cfg.KeyCount)
+	}
+	if cfg.Concurrency < 1 {
+		return nil, errors.Errorf("concurrency must be at least 1, got %d", cfg.Concurrency)
+	}
+
+	return &EtcdStressTest{
+		cfg:          cfg,
+		k8sClientCfg: k8sClientCfg,
+		log:          log.WithField("service", "test:etcd-stress"),
+	}, nil
+}
+
+// Execute executes the etcd stress test
+func (t *EtcdStressTest) Execute(ctx context.Context) (retErr error) {
+	runner.StartStep(ctx, "creating etcd client")
+	cli, err := t.newClient()
+	if err != nil {
+		return errors.Wrap(err, "while creating etcd client")
+	}
+
+	runner.StartStep(ctx, "reading etcd status")
+	before, err := cli.Status(ctx)
+	if err != nil {
+		return errors.Wrap(err, "while reading etcd status")
+	}
+
+	prefix := fmt.Sprintf("%s%s/", etcdKeyPrefix, runner.RunID(ctx))
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		runner.StartStep(ctx, "deleting keys")
+		if _, err := cli.DeletePrefix(ctx, prefix); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while deleting written keys"))
+		}
+	}()
+
+	runner.StartStep(ctx, "writing keys")
+	writes, first, err := t.writeKeys(ctx, cli, prefix)
+	if err != nil {
+		return err
+	}
+
+	runner.StartStep(ctx, "reading keys")
+	reads, err := t.readKeys(ctx, cli, prefix)
+	if err != nil {
+		return err
+	}
+
+	runner.StartStep(ctx, "checking compaction")
+	compacted, err := t.compactedDuringTest(ctx, cli, first)
+	if err != nil {
+		return err
+	}
+
+	after, err := cli.Status(ctx)
+	if err != nil {
+		return errors.Wrap(err, "while reading etcd status")
+	}
+
+	report := fmt.Sprintf("etcd %s, writes [%s], reads [%s], revisions: %d, DB size: %d -> %d bytes, compacted during test: %v",
+		after.Version, writes, reads, after.Revision-before.Revision, before.DBSize, after.DBSize, compacted)
+	t.log.WithField("ID", runner.RunID(ctx)).Infof("Etcd stress results: %s", report)
+
+	var breaches []string
+	if t.cfg.MaxWriteP99 > 0 && writes.P99 > t.cfg.MaxWriteP99 {
+		breaches = append(breaches, fmt.Sprintf("write p99 %v exceeds %v", writes.P99, t.cfg.MaxWriteP99))
+	}
+	if t.cfg.MaxReadP99 > 0 && reads.P99 > t.cfg.MaxReadP99 {
+		breaches = append(breaches, fmt.Sprintf("read p99 %v exceeds %v", reads.P99, t.cfg.MaxReadP99))
+	}
+	if len(breaches) > 0 {
+		return fmt.Errorf("etcd latency thresholds exceeded: %v\n%s", breaches, report)
+	}
+
+	return nil
+}
+
+// Name returns the name of the stress test
+func (t *EtcdStressTest) Name() string {
+	return "Etcd Stress"
+}
+
+func (t *EtcdStressTest) newClient() (*etcd.Client, error) {
+	if t.cfg.SecretName == "" {
+		return etcd.NewClient(t.cfg.Endpoint, t.cfg.APIPrefix, nil, t.cfg.RequestTimeout)
+	}
+
+	k8sCli, err := kubernetes.NewForConfig(t.k8sClientCfg)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := k8sCli.CoreV1().Secrets(t.cfg.SecretNamespace).Get(t.cfg.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "while getting Secret %s/%s with TLS material", t.cfg.SecretNamespace, t.cfg.SecretName)
+	}
+
+	return etcd.NewClient(t.cfg.Endpoint, t.cfg.APIPrefix, &etcd.TLSConfig{
+		Cert:               secret.Data[t.cfg.SecretCertKey],
+		Key:                secret.Data[t.cfg.SecretKeyKey],
+		CA:                 secret.Data[t.cfg.SecretCAKey],
+		InsecureSkipVerify: t.cfg.InsecureSkipVerify,
+	}, t.cfg.RequestTimeout)
+}
+
+// writeKeys writes the configured number of keys and returns the latency summary and the first written key
+func (t *EtcdStressTest) writeKeys(ctx context.Context, cli *etcd.Client, prefix string) (stats.Summary, etcd.KeyValue, error) {
+	var (
+		latencies stats.Latencies
+		mu        sync.Mutex
+		first     etcd.KeyValue
+	)
+
+	err := t.forEachKey(ctx, prefix, func(key string) error {
+		value := make([]byte, t.cfg.KeySize)
+		if _, err := rand.Read(value); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		rev, err := cli.Put(ctx, key, value)
+		if err != nil {
+			return errors.Wrapf(err, "while writing key %s", key)
+		}
+		latencies.Add(time.Since(start))
+
+		mu.Lock()
+		if first.ModRevision == 0 || rev < first.ModRevision {
+			first = etcd.KeyValue{Key: key, ModRevision: rev}
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	return latencies.Summary(), first, err
+}
+
+func (t *EtcdStressTest) readKeys(ctx context.Context, cli *etcd.Client, prefix string) (stats.Summary, error) {
+	var latencies stats.Latencies
+
+	err := t.forEachKey(ctx, prefix, func(key string) error {
+		start := time.Now()
+		kv, err := cli.Get(ctx, key, 0)
+		if err != nil {
+			return errors.Wrapf(err, "while reading key %s", key)
+		}
+		latencies.Add(time.Since(start))
+
+		if len(kv.Value) != t.cfg.KeySize {
+			return fmt.Errorf("key %s has %d bytes, expected %d", key, len(kv.Value), t.cfg.KeySize)
+		}
+		return nil
+	})
+
+	return latencies.Summary(), err
+}
+
+// compactedDuringTest reads the first written key at its revision. Compaction of that revision
+// is not a failure, but it is reported, as it affects the measured latency.
+func (t *EtcdStressTest) compactedDuringTest(ctx context.Context, cli *etcd.Client, first etcd.KeyValue) (bool, error) {
+	_, err := cli.Get(ctx, first.Key, first.ModRevision)
+	switch {
+	case etcd.IsCompacted(err):
+		return true, nil
+	case err != nil:
+		return false, errors.Wrapf(err, "while reading key %s at revision %d", first.Key, first.ModRevision)
+	default:
+		return false, nil
+	}
+}
+
+// forEachKey executes given function for each key by the configured number of workers and stops on the first error
+func (t *EtcdStressTest) forEachKey(ctx context.Context, prefix string, fn func(key string) error) error {
+	keys := make(chan string)
+	errCh := make(chan error, t.cfg.Concurrency)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for w := 0; w < t.cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				if err := fn(key); err != nil {
+					errCh <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+sendingLoop:
+	for i := 0; i < t.cfg.KeyCount; i++ {
+		select {
+		case keys <- t.key(prefix, i):
+		case <-ctx.Done():
+			break sendingLoop
+		}
+	}
+	close(keys)
+	wg.Wait()
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+func (t *EtcdStressTest) key(prefix string, i int) string {
+	return fmt.Sprintf("%s%08d", prefix, i)
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
+	"github.com/pkg/errors"
+	appsTypes "k8s.io/api/apps/v1beta1"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Modes in which the fake broker is run
+const (
+	// FakeBrokerModeDeployment runs the fake broker as a Deployment in the test namespace
+	FakeBrokerModeDeployment = "deployment"
+	// FakeBrokerModeInProcess uses the fake broker served by the tester itself
+	FakeBrokerModeInProcess = "in-process"
+)
+
+const (
+	fakeBrokerName = "fake-broker"
+	fakeBrokerPort = 8080
+)
+
+// FakeBrokerConfig holds configuration of the fake broker used by tests instead of the real one
+type FakeBrokerConfig struct {
+	Mode string `envconfig:"default=deployment"`
+	// Image is the image of the tester which is used to run the fake broker in the deployment mode
+	Image string `envconfig:"optional"`
+	// InProcessURL is the address under which the broker served by the tester is available for Service Catalog,
+	// e.g. `http://stressor.kyma-system.svc.cluster.local/fake-broker`
+	InProcessURL string `envconfig:"optional"`
+	Broker       fakebroker.Config
+}
+
+// deployFakeBroker runs the fake broker according to the configured mode and returns its URL
+func (ts *testSuite) deployFakeBroker(ctx context.Context, cfg FakeBrokerConfig, timeout time.Duration) (string, error) {
+	switch cfg.Mode {
+	case FakeBrokerModeInProcess:
+		if cfg.InProcessURL == "" {
+			return "", errors.New("URL of the in-process fake broker is not configured")
+		}
+		return cfg.InProcessURL, nil
+	case FakeBrokerModeDeployment:
+		if cfg.Image == "" {
+			return "", errors.New("image of the fake broker is not configured")
+		}
+	default:
+		return "", errors.Errorf("unknown fake broker mode %q", cfg.Mode)
+	}
+
+	labels := map[string]string{"app": fakeBrokerName}
+	if _, err := ts.k8sCli.AppsV1beta1().Deployments(ts.namespace).Create(ts.fakeBrokerDeployment(cfg, labels)); err != nil {
+		return "", errors.Wrap(err, "while creating fake broker Deployment")
+	}
+	if _, err := ts.k8sCli.CoreV1().Services(ts.namespace).Create(ts.fakeBrokerService(labels)); err != nil {
+		return "", errors.Wrap(err, "while creating fake broker Service")
+	}
+
+	err := repeatUntilTimeout(ctx, func() error {
+		deploy, err := ts.k8sCli.AppsV1beta1().Deployments(ts.namespace).Get(fakeBrokerName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if deploy.Status.AvailableReplicas < 1 {
+			return fmt.Errorf("Deployment %s/%s is not available. Status: %+v", deploy.Namespace, deploy.Name, deploy.Status)
+		}
+		return nil
+	}, timeout)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local", fakeBrokerName, ts.namespace), nil
+}
+
+func (ts *testSuite) fakeBrokerService(labels map[string]string) *k8sCoreTypes.Service {
+	return &k8sCoreTypes.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fakeBrokerName,
+		},
+		Spec: k8sCoreTypes.ServiceSpec{
+			Selector: labels,
+			Ports: []k8sCoreTypes.ServicePort{
+				{
+					Name:       "http",
+					Protocol:   k8sCoreTypes.ProtocolTCP,
+					Port:       80,
+					TargetPort: intstr.FromInt(fakeBrokerPort),
+				},
+			},
+		},
+	}
+}
+
+func (ts *testSuite) fakeBrokerDeployment(cfg FakeBrokerConfig, labels map[string]string) *appsTypes.Deployment {
+	env := []k8sCoreTypes.EnvVar{
+		{Name: "APP_PORT", Value: fmt.Sprintf("%d", fakeBrokerPort)},
+	}
+	for name, value := range cfg.Broker.EnvVars("APP_BROKER") {
+		env = append(env, k8sCoreTypes.EnvVar{Name: name, Value: value})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+
+	var replicas int32 = 1
+	return &appsTypes.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fakeBrokerName,
+		},
+		Spec: appsTypes.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Replicas: &replicas,
+			Template: k8sCoreTypes.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+					Annotations: map[string]string{
+						"sidecar.istio.io/inject": "false",
+					},
+				},
+				Spec: k8sCoreTypes.PodSpec{
+					Containers: []k8sCoreTypes.Container{
+						{
+							Name:    "broker",
+							Image:   cfg.Image,
+							Command: []string{"/app/main", "fake-broker"},
+							Env:     env,
+							Ports: []k8sCoreTypes.ContainerPort{
+								{
+									Name:          "http",
+									Protocol:      k8sCoreTypes.ProtocolTCP,
+									ContainerPort: fakeBrokerPort,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
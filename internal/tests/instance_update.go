@@ -0,0 +1,305 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	restclient "k8s.io/client-go/rest"
+)
+
+// InstanceUpdateTest tests the Service Catalog update path:
+// - Creating ServiceInstance and ServiceBinding
+// - Changing the plan and the parameters of the ServiceInstance
+// - Waiting until the ServiceInstance status reflects the updated plan and parameters
+// - Checking that the ServiceBinding is still ready and its Secret is not empty
+//
+// Prerequisite:
+//   - ClusterServiceClass with updatable plans is available, it is checked before the ServiceInstance is created
+//     when the plan is updated, or UseFakeBroker is set to true, so the fake broker is registered in the test namespace
+type InstanceUpdateTest struct {
+	k8sClientCfg  *restclient.Config
+	cfg           InstanceUpdateTestConfig
+	fakeBrokerCfg FakeBrokerConfig
+	secretAccess  SecretAccessConfig
+	diagnostics   DiagnosticsCollector
+}
+
+// InstanceUpdateTestConfig holds possible configuration for test
+type InstanceUpdateTestConfig struct {
+	Enabled                  bool   `envconfig:"default=false"`
+	ServiceClassExternalName string `envconfig:"default=redis"`
+	ServicePlanExternalName  string `envconfig:"default=micro"`
+	// UpdatedPlanExternalName is the plan set by the update. The plan is not changed when empty.
+	UpdatedPlanExternalName string `envconfig:"default=enterprise"`
+	// UpdateParameters is the JSON object set as parameters by the update. The parameters are not changed when empty.
+	UpdateParameters string `envconfig:"optional"`
+	// UseFakeBroker defines if the fake broker is registered in the test namespace and its
+	// ServiceClass is used instead of the ClusterServiceClass
+	UseFakeBroker bool `envconfig:"default=false"`
+	Test          runner.ScheduleConfig
+}
+
+// NewInstanceUpdateTest returns new instance of InstanceUpdateTest
+func NewInstanceUpdateTest(cfg InstanceUpdateTestConfig, fakeBrokerCfg FakeBrokerConfig, secretAccess SecretAccessConfig, k8sClientCfg *restclient.Config, diagnostics DiagnosticsCollector) *InstanceUpdateTest {
+	if cfg.UseFakeBroker {
+		cfg.ServiceClassExternalName = fakebroker.ServiceName
+		cfg.ServicePlanExternalName = fakebroker.PlanMicro
+		cfg.UpdatedPlanExternalName = fakebroker.PlanStandard
+	}
+
+	return &InstanceUpdateTest{
+		k8sClientCfg:  k8sClientCfg,
+		cfg:           cfg,
+		fakeBrokerCfg: fakeBrokerCfg,
+		secretAccess:  secretAccess,
+		diagnostics:   diagnostics,
+	}
+}
+
+// Execute executes the instance update test
+func (t *InstanceUpdateTest) Execute(ctx context.Context) (retErr error) {
+	startTime := time.Now()
+
+	if t.cfg.UpdatedPlanExternalName == "" && t.cfg.UpdateParameters == "" {
+		return errors.New("nothing to update, neither the updated plan nor the update parameters are set")
+	}
+	if t.cfg.UpdateParameters != "" && !json.Valid([]byte(t.cfg.UpdateParameters)) {
+		return fmt.Errorf("update parameters are not a valid JSON: %s", t.cfg.UpdateParameters)
+	}
+
+	// setup
+	runner.StartStep(ctx, "creating test suite")
+	ts, err := newTestSuite(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating test suite")
+	}
+
+	runner.StartStep(ctx, "creating test namespace")
+	if err := ts.createTestNamespace(ctx); err != nil {
+		return errors.Wrap(err, "while creating test namespace")
+	}
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		if retErr != nil {
+			runner.StartStep(ctx, "collecting diagnostics")
+			retErr = collectDiagnostics(ctx, t.diagnostics, ts.namespace, startTime, retErr)
+		}
+
+		runner.StartStep(ctx, "deleting test namespace")
+		if err := ts.ensureTestNamespaceIsDeleted(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
+		}
+	}()
+
+	runner.StartStep(ctx, "granting access to Secrets")
+	if err := grantSecretAccess(ts.k8sCli, t.secretAccess, ts.namespace); err != nil {
+		return errors.Wrap(err, "while granting access to Secrets")
+	}
+
+	var steps []step
+	if t.cfg.UseFakeBroker {
+		steps = append(steps,
+			step{name: "deploying fake broker", fn: func(ctx context.Context, timeout time.Duration) error {
+				url, err := ts.deployFakeBroker(ctx, t.fakeBrokerCfg, timeout)
+				if err != nil {
+					return err
+				}
+				return ts.registerAndWaitForServiceBroker(ctx, url, timeout)
+			}},
+			step{name: "waiting for ServiceClass and ServicePlans", fn: func(ctx context.Context, timeout time.Duration) error {
+				if err := ts.waitForServiceClassAndPlan(ctx, t.cfg.ServiceClassExternalName, t.cfg.ServicePlanExternalName, timeout); err != nil {
+					return err
+				}
+				return ts.waitForServiceClassAndPlan(ctx, t.cfg.ServiceClassExternalName, t.cfg.UpdatedPlanExternalName, timeout)
+			}},
+		)
+	}
+
+	if t.cfg.UpdatedPlanExternalName != "" {
+		steps = append(steps, step{name: "checking that plans are updatable", fn: t.assertPlanUpdatable(ts)})
+	}
+
+	steps = append(steps,
+		step{name: "creating ServiceInstance", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.createAndWaitForInstance(ctx, t.planReference(t.cfg.ServicePlanExternalName), timeout)
+		}},
+		step{name: "creating ServiceBinding", fn: ts.createAndWaitForRedisServiceBinding},
+		step{name: "updating ServiceInstance", fn: t.updateAndWaitForInstance(ts)},
+		step{name: "verifying ServiceBinding", fn: func(ctx context.Context, timeout time.Duration) error {
+			return ts.assertBindingStillWorks()
+		}},
+	)
+
+	return executeSteps(ctx, timeoutPerStep, steps...)
+}
+
+// Name returns the name of the stress test
+func (t *InstanceUpdateTest) Name() string {
+	return "ServiceInstance Update"
+}
+
+func (t *InstanceUpdateTest) planReference(planExternalName string) scTypes.PlanReference {
+	if t.cfg.UseFakeBroker {
+		return scTypes.PlanReference{
+			ServiceClassExternalName: t.cfg.ServiceClassExternalName,
+			ServicePlanExternalName:  planExternalName,
+		}
+	}
+	return scTypes.PlanReference{
+		ClusterServiceClassExternalName: t.cfg.ServiceClassExternalName,
+		ClusterServicePlanExternalName:  planExternalName,
+	}
+}
+
+func (t *InstanceUpdateTest) updateAndWaitForInstance(ts *testSuite) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		siClient := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace)
+
+		var (
+			generation int64
+			// prevChecksum is the checksum of the parameters sent to the broker before the update
+			prevChecksum string
+		)
+		err := repeatUntilTimeout(ctx, func() error {
+			si, err := siClient.Get(ts.serviceInstanceName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if si.Status.ExternalProperties != nil {
+				prevChecksum = si.Status.ExternalProperties.ParametersChecksum
+			}
+
+			if t.cfg.UpdatedPlanExternalName != "" {
+				si.Spec.PlanReference = t.planReference(t.cfg.UpdatedPlanExternalName)
+			}
+			if t.cfg.UpdateParameters != "" {
+				si.Spec.Parameters = &runtime.RawExtension{Raw: []byte(t.cfg.UpdateParameters)}
+			}
+
+			updated, err := siClient.Update(si)
+			if err != nil {
+				return err
+			}
+			generation = updated.Generation
+			return nil
+		}, timeout)
+		if err != nil {
+			return errors.Wrap(err, "while updating ServiceInstance")
+		}
+
+		return repeatUntilTimeout(ctx, func() error {
+			si, err := siClient.Get(ts.serviceInstanceName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if si.Status.ObservedGeneration < generation {
+				return fmt.Errorf("ServiceInstance %s/%s observed generation %d, expected %d", si.Namespace, si.Name, si.Status.ObservedGeneration, generation)
+			}
+			if si.Status.ReconciledGeneration < generation {
+				return fmt.Errorf("ServiceInstance %s/%s reconciled generation %d, expected %d", si.Namespace, si.Name, si.Status.ReconciledGeneration, generation)
+			}
+			if si.Status.CurrentOperation != "" {
+				return fmt.Errorf("ServiceInstance %s/%s operation %s is in progress", si.Namespace, si.Name, si.Status.CurrentOperation)
+			}
+			ready, err := instanceReady(si)
+			if err != nil {
+				return err
+			}
+			if !ready {
+				return fmt.Errorf("ServiceInstance %s/%s is not in ready state, last condition: %s", si.Namespace, si.Name, lastInstanceCondition(si.Status.Conditions))
+			}
+
+			if t.cfg.UpdatedPlanExternalName != "" {
+				if plan := t.externalPlanName(si.Status.ExternalProperties); plan != t.cfg.UpdatedPlanExternalName {
+					return fmt.Errorf("ServiceInstance %s/%s is on the plan %q, expected %q", si.Namespace, si.Name, plan, t.cfg.UpdatedPlanExternalName)
+				}
+			}
+			if t.cfg.UpdateParameters != "" {
+				if si.Status.ExternalProperties == nil || si.Status.ExternalProperties.ParametersChecksum == prevChecksum {
+					return fmt.Errorf("ServiceInstance %s/%s parameters checksum did not change after the update", si.Namespace, si.Name)
+				}
+			}
+			return nil
+		}, timeout)
+	}
+}
+
+// assertPlanUpdatable checks that the tested class allows changing the plan, so the misconfiguration
+// is reported before any resource is created
+func (t *InstanceUpdateTest) assertPlanUpdatable(ts *testSuite) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		sc := ts.scCli.ServicecatalogV1beta1()
+
+		var specs []scTypes.CommonServiceClassSpec
+		if t.cfg.UseFakeBroker {
+			classes, err := sc.ServiceClasses(ts.namespace).List(metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "while listing ServiceClasses")
+			}
+			for _, c := range classes.Items {
+				specs = append(specs, c.Spec.CommonServiceClassSpec)
+			}
+		} else {
+			classes, err := sc.ClusterServiceClasses().List(metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "while listing ClusterServiceClasses")
+			}
+			for _, c := range classes.Items {
+				specs = append(specs, c.Spec.CommonServiceClassSpec)
+			}
+		}
+
+		for _, spec := range specs {
+			if spec.ExternalName != t.cfg.ServiceClassExternalName {
+				continue
+			}
+			if !spec.PlanUpdatable {
+				return errors.Errorf("plans of the class %q are not updatable, choose other class or leave the updated plan empty", t.cfg.ServiceClassExternalName)
+			}
+			return nil
+		}
+		return errors.Errorf("class %q not found", t.cfg.ServiceClassExternalName)
+	}
+}
+
+// externalPlanName returns the name of the plan the broker knows the ServiceInstance to be on
+func (t *InstanceUpdateTest) externalPlanName(props *scTypes.ServiceInstancePropertiesState) string {
+	switch {
+	case props == nil:
+		return ""
+	case t.cfg.UseFakeBroker:
+		return props.ServicePlanExternalName
+	default:
+		return props.ClusterServicePlanExternalName
+	}
+}
+
+// assertBindingStillWorks checks that the ServiceBinding is ready and its Secret has no empty values
+func (ts *testSuite) assertBindingStillWorks() error {
+	b, err := ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace).Get(ts.bindingName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "while getting ServiceBinding")
+	}
+	ready, err := bindingReady(b)
+	if err != nil {
+		return err
+	}
+	if !ready {
+		return fmt.Errorf("ServiceBinding %s/%s is not in ready state, last condition: %s", b.Namespace, b.Name, lastBindingCondition(b.Status.Conditions))
+	}
+
+	_, err = ts.bindingSecret()
+	return err
+}
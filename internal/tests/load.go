@@ -0,0 +1,322 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/stats"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+// LoadTest provisions many ServiceInstances and ServiceBindings concurrently across many namespaces
+// and measures the time after which they become ready:
+// - creation of instances is spread evenly over the ramp duration
+// - bindings are distributed over instances and created as soon as their instance is ready
+// - the test fails when the error rate or the time-to-ready percentiles exceed the SLO
+type LoadTest struct {
+	cfg          LoadTestConfig
+	k8sClientCfg *restclient.Config
+	log          logrus.FieldLogger
+}
+
+// LoadTestConfig holds possible configuration for test
+type LoadTestConfig struct {
+	Enabled    bool `envconfig:"default=false"`
+	Namespaces int  `envconfig:"default=3"`
+	Instances  int  `envconfig:"default=10"`
+	Bindings   int  `envconfig:"default=10"`
+	// Ramp is the duration over which the creation of instances is spread
+	Ramp                            time.Duration `envconfig:"default=30s"`
+	ClusterServiceClassExternalName string        `envconfig:"default=redis"`
+	ClusterServicePlanExternalName  string        `envconfig:"default=micro"`
+	ReadyTimeout                    time.Duration `envconfig:"default=5m"`
+	// SLO, the zero value disables the check
+	MaxErrorRate   float64       `envconfig:"default=0.05"`
+	MaxInstanceP90 time.Duration `envconfig:"default=2m"`
+	MaxBindingP90  time.Duration `envconfig:"default=1m"`
+	Test           runner.ScheduleConfig
+}
+
+// NewLoadTest returns new instance of LoadTest
+func NewLoadTest(cfg LoadTestConfig, k8sClientCfg *restclient.Config, log logrus.FieldLogger) (*LoadTest, error) {
+	if cfg.Namespaces < 1 {
+		return nil, errors.Errorf("namespaces must be at least 1, got %d", cfg.Namespaces)
+	}
+	if cfg.Instances < 1 {
+		return nil, errors.Errorf("instances must be at least 1, got %d", cfg.Instances)
+	}
+	if cfg.Bindings < 0 {
+		return nil, errors.Errorf("bindings must not be negative, got %d", cfg.Bindings)
+	}
+	if cfg.Ramp < 0 {
+		return nil, errors.Errorf("ramp must not be negative, got %v", cfg.Ramp)
+	}
+
+	return &LoadTest{
+		cfg:          cfg,
+		k8sClientCfg: k8sClientCfg,
+		log:          log.WithField("service", "test:load"),
+	}, nil
+}
+
+// loadRun holds the state of a single load test execution
+type loadRun struct {
+	cfg    LoadTestConfig
+	k8sCli kubernetes.Interface
+	scCli  scClient.Interface
+
+	namespaces []string
+
+	instances stats.Latencies
+	bindings  stats.Latencies
+
+	errsMu sync.Mutex
+	errs   []string
+	// failed is the number of instances and bindings which failed or were not created because of errors
+	failed int
+}
+
+// Execute executes the load test
+func (t *LoadTest) Execute(ctx context.Context) (retErr error) {
+	runner.StartStep(ctx, "creating clients")
+	k8sCli, err := kubernetes.NewForConfig(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating k8s client")
+	}
+	scCli, err := scClient.NewForConfig(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating Service Catalog client")
+	}
+
+	r := &loadRun{cfg: t.cfg, k8sCli: k8sCli, scCli: scCli}
+
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		runner.StartStep(ctx, "deleting test namespaces")
+		if err := r.deleteNamespaces(ctx); err != nil {
+			retErr = appendErr(retErr, err)
+		}
+	}()
+
+	runner.StartStep(ctx, "creating test namespaces")
+	if err := r.createNamespaces(ctx); err != nil {
+		return errors.Wrap(err, "while creating test namespaces")
+	}
+
+	runner.StartStep(ctx, "provisioning instances and bindings")
+	r.provision(ctx)
+
+	return t.evaluate(ctx, r)
+}
+
+// Name returns the name of the stress test
+func (t *LoadTest) Name() string {
+	return "Service Catalog Load"
+}
+
+func (t *LoadTest) evaluate(ctx context.Context, r *loadRun) error {
+	instances, bindings := r.instances.Summary(), r.bindings.Summary()
+	total := t.cfg.Instances + t.cfg.Bindings
+	errorRate := 0.0
+	if total > 0 {
+		errorRate = float64(r.failed) / float64(total)
+	}
+
+	report := fmt.Sprintf("instances time-to-ready [%s], bindings time-to-ready [%s], errors: %d/%d (%.1f%%)",
+		instances, bindings, r.failed, total, errorRate*100)
+	t.log.WithField("ID", runner.RunID(ctx)).Infof("Load results: %s", report)
+
+	var breaches []string
+	if t.cfg.MaxErrorRate > 0 && errorRate > t.cfg.MaxErrorRate {
+		breaches = append(breaches, fmt.Sprintf("error rate %.1f%% exceeds %.1f%%", errorRate*100, t.cfg.MaxErrorRate*100))
+	}
+	if t.cfg.MaxInstanceP90 > 0 && instances.P90 > t.cfg.MaxInstanceP90 {
+		breaches = append(breaches, fmt.Sprintf("instance p90 %v exceeds %v", instances.P90, t.cfg.MaxInstanceP90))
+	}
+	if t.cfg.MaxBindingP90 > 0 && bindings.P90 > t.cfg.MaxBindingP90 {
+		breaches = append(breaches, fmt.Sprintf("binding p90 %v exceeds %v", bindings.P90, t.cfg.MaxBindingP90))
+	}
+	if len(breaches) == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("load SLO breached: %v\n%s", breaches, report)
+	return errors.New(withReportedErrs(msg, r.errs))
+}
+
+func (r *loadRun) createNamespaces(ctx context.Context) error {
+	randID := rand.String(5)
+	for i := 0; i < r.cfg.Namespaces; i++ {
+		name := fmt.Sprintf("load-test-%s-%d", randID, i)
+		_, err := r.k8sCli.CoreV1().Namespaces().Create(&k8sCoreTypes.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: testNamespaceLabels(ctx),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		r.namespaces = append(r.namespaces, name)
+	}
+	return nil
+}
+
+func (r *loadRun) deleteNamespaces(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, ns := range r.namespaces {
+		wg.Add(1)
+		go func(ns string) {
+			defer wg.Done()
+			if err := deleteNamespaceAndWait(ctx, r.k8sCli, r.scCli, ns, namespaceDeletionTimeout); err != nil {
+				mu.Lock()
+				errs = append(errs, errors.Wrapf(err, "while deleting namespace %s", ns))
+				mu.Unlock()
+			}
+		}(ns)
+	}
+	wg.Wait()
+
+	return appendErr(nil, errs...)
+}
+
+// provision creates instances spread over the ramp duration and waits until all instances and bindings are processed
+func (r *loadRun) provision(ctx context.Context) {
+	if r.cfg.Instances == 0 || len(r.namespaces) == 0 {
+		return
+	}
+
+	// bindings are distributed over instances in round-robin
+	bindingsPerInstance := make([]int, r.cfg.Instances)
+	for i := 0; i < r.cfg.Bindings; i++ {
+		bindingsPerInstance[i%r.cfg.Instances]++
+	}
+
+	interval := r.cfg.Ramp / time.Duration(r.cfg.Instances)
+	var wg sync.WaitGroup
+	for i := 0; i < r.cfg.Instances; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				// instances which were not created are counted as failed together with their bindings
+				unstarted := 0
+				for j := i; j < r.cfg.Instances; j++ {
+					unstarted += 1 + bindingsPerInstance[j]
+				}
+				r.recordFailed(unstarted, errors.Errorf("provisioning interrupted, %d instances not created: %v", r.cfg.Instances-i, ctx.Err()))
+				wg.Wait()
+				return
+			case <-time.After(interval):
+			}
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.provisionInstance(ctx, r.namespaces[i%len(r.namespaces)], fmt.Sprintf("load-instance-%d", i), bindingsPerInstance[i])
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (r *loadRun) provisionInstance(ctx context.Context, namespace, name string, bindings int) {
+	siClient := r.scCli.ServicecatalogV1beta1().ServiceInstances(namespace)
+
+	start := time.Now()
+	_, err := siClient.Create(&scTypes.ServiceInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: scTypes.ServiceInstanceSpec{
+			PlanReference: scTypes.PlanReference{
+				ClusterServiceClassExternalName: r.cfg.ClusterServiceClassExternalName,
+				ClusterServicePlanExternalName:  r.cfg.ClusterServicePlanExternalName,
+			},
+		},
+	})
+	if err != nil {
+		// bindings of the failed instance are not created, so they are counted as failed too
+		r.recordFailed(1+bindings, errors.Wrapf(err, "while creating ServiceInstance %s/%s, %d bindings skipped", namespace, name, bindings))
+		return
+	}
+
+	err = repeatUntilTimeout(ctx, func() error {
+		si, err := siClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		return ensureReady(instanceReady(si))
+	}, r.cfg.ReadyTimeout)
+	if err != nil {
+		r.recordFailed(1+bindings, errors.Wrapf(err, "while waiting for ServiceInstance %s/%s, %d bindings skipped", namespace, name, bindings))
+		return
+	}
+	r.instances.Add(time.Since(start))
+
+	var wg sync.WaitGroup
+	for b := 0; b < bindings; b++ {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			r.createBinding(ctx, namespace, fmt.Sprintf("%s-binding-%d", name, b), name)
+		}(b)
+	}
+	wg.Wait()
+}
+
+func (r *loadRun) createBinding(ctx context.Context, namespace, name, instanceName string) {
+	bindingClient := r.scCli.ServicecatalogV1beta1().ServiceBindings(namespace)
+
+	start := time.Now()
+	_, err := bindingClient.Create(&scTypes.ServiceBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: scTypes.ServiceBindingSpec{
+			ServiceInstanceRef: scTypes.LocalObjectReference{Name: instanceName},
+		},
+	})
+	if err != nil {
+		r.recordErr(errors.Wrapf(err, "while creating ServiceBinding %s/%s", namespace, name))
+		return
+	}
+
+	err = repeatUntilTimeout(ctx, func() error {
+		b, err := bindingClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		return ensureReady(bindingReady(b))
+	}, r.cfg.ReadyTimeout)
+	if err != nil {
+		r.recordErr(errors.Wrapf(err, "while waiting for ServiceBinding %s/%s", namespace, name))
+		return
+	}
+	r.bindings.Add(time.Since(start))
+}
+
+func (r *loadRun) recordErr(err error) {
+	r.recordFailed(1, err)
+}
+
+// recordFailed records the error which caused given number of instances and bindings to fail
+func (r *loadRun) recordFailed(count int, err error) {
+	r.errsMu.Lock()
+	r.errs = append(r.errs, err.Error())
+	r.failed += count
+	r.errsMu.Unlock()
+}
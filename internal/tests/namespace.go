@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	namespaceDeletionTimeout = 5 * time.Minute
+
+	namespaceDeletionInitialInterval = time.Second
+	namespaceDeletionMaxInterval     = 15 * time.Second
+)
+
+// testNamespaceLabels returns labels for the namespace created by the test executed with given context
+func testNamespaceLabels(ctx context.Context) map[string]string {
+	return map[string]string{
+		"env":                "true",
+		labels.TestNamespace: "true",
+		labels.RunID:         runner.RunID(ctx),
+		labels.CreatedAt:     strconv.FormatInt(time.Now().Unix(), 10),
+	}
+}
+
+// deleteNamespaceAndWait deletes the namespace and waits with exponential backoff until it is removed.
+// When the namespace is not removed in given timeout, the returned error describes resources and finalizers
+// which block the namespace deletion.
+func deleteNamespaceAndWait(ctx context.Context, k8sCli kubernetes.Interface, scCli scClient.Interface, name string, timeout time.Duration) error {
+	nsClient := k8sCli.CoreV1().Namespaces()
+	if err := nsClient.Delete(name, &metav1.DeleteOptions{}); err != nil && !apiErrors.IsNotFound(err) {
+		return err
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	interval := namespaceDeletionInitialInterval
+
+	for {
+		_, err := nsClient.Get(name, metav1.GetOptions{})
+		switch {
+		case apiErrors.IsNotFound(err):
+			return nil
+		case err != nil:
+			return errors.Wrapf(err, "while getting namespace %s", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("Test context done: %v. Test namespace %s can be still in Terminating phase. %s",
+				ctx.Err(), name, namespaceDeletionBlockers(k8sCli, scCli, name))
+		case <-deadline.C:
+			return errors.Errorf("Test namespace %s was not deleted in %v. %s",
+				name, timeout, namespaceDeletionBlockers(k8sCli, scCli, name))
+		case <-time.After(interval):
+		}
+
+		interval *= 2
+		if interval > namespaceDeletionMaxInterval {
+			interval = namespaceDeletionMaxInterval
+		}
+	}
+}
+
+// namespaceDeletionBlockers describes resources and finalizers which are still present in the namespace
+func namespaceDeletionBlockers(k8sCli kubernetes.Interface, scCli scClient.Interface, name string) string {
+	out := &bytes.Buffer{}
+	fmt.Fprint(out, "Blocking resources:")
+
+	ns, err := k8sCli.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(out, " [cannot get namespace: %v]", err)
+	} else {
+		fmt.Fprintf(out, " [Namespace %s, phase: %s, finalizers: %v]", ns.Name, ns.Status.Phase, ns.Spec.Finalizers)
+	}
+
+	instances, err := scCli.ServicecatalogV1beta1().ServiceInstances(name).List(metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(out, " [cannot list ServiceInstances: %v]", err)
+	} else {
+		for _, si := range instances.Items {
+			fmt.Fprintf(out, " [ServiceInstance %s, finalizers: %v, async operation in progress: %t, current operation: %q, deprovision status: %q, %s]",
+				si.Name, si.Finalizers, si.Status.AsyncOpInProgress, si.Status.CurrentOperation, si.Status.DeprovisionStatus, lastInstanceCondition(si.Status.Conditions))
+		}
+	}
+
+	bindings, err := scCli.ServicecatalogV1beta1().ServiceBindings(name).List(metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(out, " [cannot list ServiceBindings: %v]", err)
+	} else {
+		for _, b := range bindings.Items {
+			fmt.Fprintf(out, " [ServiceBinding %s, finalizers: %v, current operation: %q, %s]",
+				b.Name, b.Finalizers, b.Status.CurrentOperation, lastBindingCondition(b.Status.Conditions))
+		}
+	}
+
+	pods, err := k8sCli.CoreV1().Pods(name).List(metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(out, " [cannot list Pods: %v]", err)
+	} else {
+		for _, p := range pods.Items {
+			fmt.Fprintf(out, " [Pod %s, phase: %s, finalizers: %v]", p.Name, p.Status.Phase, p.Finalizers)
+		}
+	}
+
+	return out.String()
+}
+
+func lastInstanceCondition(conditions []scTypes.ServiceInstanceCondition) string {
+	if len(conditions) == 0 {
+		return "no conditions"
+	}
+	c := conditions[len(conditions)-1]
+	return fmt.Sprintf("condition %s=%s, reason: %s, message: %s", c.Type, c.Status, c.Reason, c.Message)
+}
+
+func lastBindingCondition(conditions []scTypes.ServiceBindingCondition) string {
+	if len(conditions) == 0 {
+		return "no conditions"
+	}
+	c := conditions[len(conditions)-1]
+	return fmt.Sprintf("condition %s=%s, reason: %s, message: %s", c.Type, c.Status, c.Reason, c.Message)
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	restclient "k8s.io/client-go/rest"
+)
+
+// Reasons of the Ready condition set by the Service Catalog controller-manager
+const (
+	reasonNonexistentServicePlan = "ReferencesNonexistentServicePlan"
+	reasonErrorWithParameters    = "ErrorWithParameters"
+	reasonNonexistentInstance    = "ReferencesNonexistentInstance"
+	reasonProvisionCallFailed    = "ProvisionCallFailed"
+)
+
+// NegativePathTest tests that the Service Catalog surfaces errors instead of hanging:
+// - ServiceInstance which references non-existent plan
+// - ServiceInstance with parameters from non-existent Secret
+// - ServiceBinding which references non-existent ServiceInstance
+// - ServiceInstance with parameters rejected by the fake broker, when UseFakeBroker is set to true
+//
+// Each scenario expects the Ready condition set to False, or the Failed condition set to True, with the specific reason.
+// All scenarios are executed, also when some of them fail.
+type NegativePathTest struct {
+	k8sClientCfg  *restclient.Config
+	cfg           NegativePathTestConfig
+	fakeBrokerCfg FakeBrokerConfig
+	diagnostics   DiagnosticsCollector
+}
+
+// NegativePathTestConfig holds possible configuration for test
+type NegativePathTestConfig struct {
+	Enabled bool `envconfig:"default=false"`
+	// ClusterServiceClassExternalName and ClusterServicePlanExternalName must exist, so only the tested error is reported
+	ClusterServiceClassExternalName string `envconfig:"default=redis"`
+	ClusterServicePlanExternalName  string `envconfig:"default=micro"`
+	// ConditionTimeout is the time in which the expected condition must be reported
+	ConditionTimeout time.Duration `envconfig:"default=2m"`
+	// UseFakeBroker defines if the fake broker is registered in the test namespace
+	// to check that the provisioning rejected by the broker is reported
+	UseFakeBroker bool `envconfig:"default=false"`
+	Test          runner.ScheduleConfig
+}
+
+// NewNegativePathTest returns new instance of NegativePathTest
+func NewNegativePathTest(cfg NegativePathTestConfig, fakeBrokerCfg FakeBrokerConfig, k8sClientCfg *restclient.Config, diagnostics DiagnosticsCollector) *NegativePathTest {
+	return &NegativePathTest{
+		k8sClientCfg:  k8sClientCfg,
+		cfg:           cfg,
+		fakeBrokerCfg: fakeBrokerCfg,
+		diagnostics:   diagnostics,
+	}
+}
+
+// Execute executes negative path Service Catalog test
+func (t *NegativePathTest) Execute(ctx context.Context) (retErr error) {
+	startTime := time.Now()
+
+	// setup
+	runner.StartStep(ctx, "creating test suite")
+	ts, err := newTestSuite(t.k8sClientCfg)
+	if err != nil {
+		return errors.Wrap(err, "while creating test suite")
+	}
+
+	runner.StartStep(ctx, "creating test namespace")
+	if err := ts.createTestNamespace(ctx); err != nil {
+		return errors.Wrap(err, "while creating test namespace")
+	}
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		if retErr != nil {
+			runner.StartStep(ctx, "collecting diagnostics")
+			retErr = collectDiagnostics(ctx, t.diagnostics, ts.namespace, startTime, retErr)
+		}
+
+		runner.StartStep(ctx, "deleting test namespace")
+		if err := ts.ensureTestNamespaceIsDeleted(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrap(err, "while ensuring that test namespace is deleted"))
+		}
+	}()
+
+	scenarios := []step{
+		{name: "creating ServiceInstance with non-existent plan", fn: t.nonexistentPlan(ts)},
+		{name: "creating ServiceInstance with invalid parameters", fn: t.invalidParameters(ts)},
+		{name: "creating ServiceBinding to non-existent ServiceInstance", fn: t.missingInstance(ts)},
+	}
+
+	if t.cfg.UseFakeBroker {
+		err := executeSteps(ctx, timeoutPerStep, step{name: "deploying fake broker", fn: func(ctx context.Context, timeout time.Duration) error {
+			url, err := ts.deployFakeBroker(ctx, t.fakeBrokerCfg, timeout)
+			if err != nil {
+				return err
+			}
+			if err := ts.registerAndWaitForServiceBroker(ctx, url, timeout); err != nil {
+				return err
+			}
+			return ts.waitForServiceClassAndPlan(ctx, fakebroker.ServiceName, fakebroker.PlanMicro, timeout)
+		}})
+		if err != nil {
+			return err
+		}
+		scenarios = append(scenarios, step{name: "creating ServiceInstance rejected by broker", fn: t.rejectedParameters(ts)})
+	}
+
+	var failed []error
+	for _, s := range scenarios {
+		if err := executeSteps(ctx, t.cfg.ConditionTimeout, s); err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return appendErr(fmt.Errorf("%d of %d negative path scenarios failed", len(failed), len(scenarios)), failed...)
+	}
+
+	return nil
+}
+
+// Name returns the name of the stress test
+func (t *NegativePathTest) Name() string {
+	return "E2E ServiceCatalog Negative Path"
+}
+
+func (t *NegativePathTest) nonexistentPlan(ts *testSuite) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		si := &scTypes.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "negative-nonexistent-plan"},
+			Spec: scTypes.ServiceInstanceSpec{
+				PlanReference: scTypes.PlanReference{
+					ClusterServiceClassExternalName: t.cfg.ClusterServiceClassExternalName,
+					ClusterServicePlanExternalName:  "non-existent-plan",
+				},
+			},
+		}
+		return ts.createInstanceAndWaitForReason(ctx, si, reasonNonexistentServicePlan, timeout)
+	}
+}
+
+func (t *NegativePathTest) invalidParameters(ts *testSuite) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		si := &scTypes.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "negative-invalid-parameters"},
+			Spec: scTypes.ServiceInstanceSpec{
+				PlanReference: scTypes.PlanReference{
+					ClusterServiceClassExternalName: t.cfg.ClusterServiceClassExternalName,
+					ClusterServicePlanExternalName:  t.cfg.ClusterServicePlanExternalName,
+				},
+				ParametersFrom: []scTypes.ParametersFromSource{
+					{SecretKeyRef: &scTypes.SecretKeyReference{Name: "non-existent-secret", Key: "parameters"}},
+				},
+			},
+		}
+		return ts.createInstanceAndWaitForReason(ctx, si, reasonErrorWithParameters, timeout)
+	}
+}
+
+func (t *NegativePathTest) rejectedParameters(ts *testSuite) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		si := &scTypes.ServiceInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "negative-rejected-parameters"},
+			Spec: scTypes.ServiceInstanceSpec{
+				PlanReference: scTypes.PlanReference{
+					ServiceClassExternalName: fakebroker.ServiceName,
+					ServicePlanExternalName:  fakebroker.PlanMicro,
+				},
+				Parameters: &runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{%q: true}`, fakebroker.RejectedParameter))},
+			},
+		}
+		return ts.createInstanceAndWaitForReason(ctx, si, reasonProvisionCallFailed, timeout)
+	}
+}
+
+func (t *NegativePathTest) missingInstance(ts *testSuite) func(ctx context.Context, timeout time.Duration) error {
+	return func(ctx context.Context, timeout time.Duration) error {
+		bindingClient := ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace)
+		b, err := bindingClient.Create(&scTypes.ServiceBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "negative-missing-instance"},
+			Spec: scTypes.ServiceBindingSpec{
+				ServiceInstanceRef: scTypes.LocalObjectReference{Name: "non-existent-instance"},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		return repeatUntilTimeout(ctx, func() error {
+			binding, err := bindingClient.Get(b.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			for _, cond := range binding.Status.Conditions {
+				if cond.Type == scTypes.ServiceBindingConditionReady && cond.Status == scTypes.ConditionFalse && cond.Reason == reasonNonexistentInstance {
+					return nil
+				}
+			}
+			return fmt.Errorf("ServiceBinding %s/%s has no Ready condition with reason %s, last condition: %s", binding.Namespace, binding.Name, reasonNonexistentInstance, lastBindingCondition(binding.Status.Conditions))
+		}, timeout)
+	}
+}
+
+// createInstanceAndWaitForReason creates the ServiceInstance and waits until its Ready condition is False,
+// or its Failed condition is True, with given reason
+func (ts *testSuite) createInstanceAndWaitForReason(ctx context.Context, si *scTypes.ServiceInstance, reason string, timeout time.Duration) error {
+	siClient := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace)
+	if _, err := siClient.Create(si); err != nil {
+		return err
+	}
+
+	return repeatUntilTimeout(ctx, func() error {
+		instance, err := siClient.Get(si.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, cond := range instance.Status.Conditions {
+			if cond.Reason != reason {
+				continue
+			}
+			switch {
+			case cond.Type == scTypes.ServiceInstanceConditionReady && cond.Status == scTypes.ConditionFalse:
+				return nil
+			case cond.Type == scTypes.ServiceInstanceConditionFailed && cond.Status == scTypes.ConditionTrue:
+				return nil
+			}
+		}
+		return fmt.Errorf("ServiceInstance %s/%s has no Ready or Failed condition with reason %s, last condition: %s", instance.Namespace, instance.Name, reason, lastInstanceCondition(instance.Status.Conditions))
+	}, timeout)
+}
@@ -0,0 +1,497 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// cliFriendlyName matches names which are allowed for services and plans by the Open Service Broker API
+var cliFriendlyName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// OSBContractTest checks if the broker fulfills the Open Service Broker API contract:
+// - X-Broker-API-Version header is required
+// - catalog schema is valid
+// - instance is provisioned, updated and deprovisioned, asynchronous operations are polled
+// - binding is created and removed
+// - repeated requests are idempotent and conflicting requests are rejected
+// - invalid requests are rejected with proper status codes
+type OSBContractTest struct {
+	cfg    OSBContractTestConfig
+	client *osb.Client
+}
+
+// OSBContractTestConfig holds possible configuration for test
+type OSBContractTestConfig struct {
+	Enabled   bool   `envconfig:"default=false"`
+	BrokerURL string `envconfig:"default=http://helm-broker.kyma-system.svc.cluster.local"`
+	Username  string `envconfig:"optional"`
+	Password  string `envconfig:"optional"`
+	// ServiceID and PlanID are used in the instance lifecycle.
+	// If not set, the first bindable service from the catalog and its first plan are used.
+	ServiceID string `envconfig:"optional"`
+	PlanID    string `envconfig:"optional"`
+	// UpdatePlanID is the plan to which the instance is updated. Update is not checked if not set.
+	UpdatePlanID string `envconfig:"optional"`
+	// ProvisionParameters are the provisioning parameters in the JSON format
+	ProvisionParameters string `envconfig:"optional"`
+	// CheckConflicts defines if conflicting provision and bind requests are checked
+	CheckConflicts     bool          `envconfig:"default=true"`
+	PollingInterval    time.Duration `envconfig:"default=5s"`
+	MaxPollingDuration time.Duration `envconfig:"default=5m"`
+	RequestTimeout     time.Duration `envconfig:"default=1m"`
+	Test               runner.ScheduleConfig
+}
+
+// NewOSBContractTest returns new instance of OSBContractTest
+func NewOSBContractTest(cfg OSBContractTestConfig) *OSBContractTest {
+	return &OSBContractTest{
+		cfg: cfg,
+		client: osb.NewClient(osb.ClientConfig{
+			URL:      cfg.BrokerURL,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			Timeout:  cfg.RequestTimeout,
+		}),
+	}
+}
+
+// osbContractSuite holds the state of a single contract check
+type osbContractSuite struct {
+	cfg    OSBContractTestConfig
+	client *osb.Client
+
+	serviceID  string
+	planID     string
+	parameters map[string]interface{}
+
+	instanceID string
+	bindingID  string
+
+	provisioned bool
+}
+
+// Execute executes the OSB contract checks
+func (t *OSBContractTest) Execute(ctx context.Context) (retErr error) {
+	s := &osbContractSuite{
+		cfg:        t.cfg,
+		client:     t.client,
+		serviceID:  t.cfg.ServiceID,
+		planID:     t.cfg.PlanID,
+		instanceID: uuid.NewV4().String(),
+		bindingID:  uuid.NewV4().String(),
+	}
+	if t.cfg.ProvisionParameters != "" {
+		if err := json.Unmarshal([]byte(t.cfg.ProvisionParameters), &s.parameters); err != nil {
+			return errors.Wrap(err, "while decoding provisioning parameters")
+		}
+	}
+
+	// clean-up
+	defer func() {
+		ctx, cancel := runner.CleanupContext(ctx)
+		defer cancel()
+
+		if !s.provisioned {
+			return
+		}
+		runner.StartStep(ctx, "cleaning up instance")
+		if err := s.deprovision(ctx); err != nil {
+			retErr = appendErr(retErr, errors.Wrapf(err, "while cleaning up instance %s", s.instanceID))
+		}
+	}()
+
+	steps := []step{
+		{name: "checking API version header", fn: s.checkVersionHeader},
+		{name: "validating catalog", fn: s.validateCatalog},
+		{name: "checking invalid requests", fn: s.checkInvalidRequests},
+		{name: "provisioning instance", fn: s.checkProvision},
+	}
+	if t.cfg.UpdatePlanID != "" {
+		steps = append(steps, step{name: "updating instance", fn: s.checkUpdate})
+	}
+	steps = append(steps,
+		step{name: "binding", fn: s.checkBind},
+		step{name: "unbinding", fn: s.checkUnbind},
+		step{name: "deprovisioning instance", fn: s.checkDeprovision},
+	)
+
+	return executeSteps(ctx, t.cfg.MaxPollingDuration, steps...)
+}
+
+// Name returns the name of the stress test
+func (t *OSBContractTest) Name() string {
+	return "OSB Contract"
+}
+
+func (s *osbContractSuite) checkVersionHeader(ctx context.Context, _ time.Duration) error {
+	resp, err := s.client.DoWithoutVersionHeader(ctx, http.MethodGet, "/v2/catalog")
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(resp.ExpectStatus(http.StatusPreconditionFailed), "catalog request without %s header", osb.APIVersionHeader)
+}
+
+func (s *osbContractSuite) validateCatalog(ctx context.Context, _ time.Duration) error {
+	resp, err := s.client.Do(ctx, http.MethodGet, "/v2/catalog", nil)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusOK); err != nil {
+		return err
+	}
+
+	var catalog osb.CatalogResponse
+	if err := resp.Decode(&catalog); err != nil {
+		return err
+	}
+
+	violations := catalogViolations(catalog)
+	if len(violations) > 0 {
+		return fmt.Errorf("catalog violates the contract:\n%s", strings.Join(violations, "\n"))
+	}
+
+	return s.selectServiceAndPlan(catalog)
+}
+
+func catalogViolations(catalog osb.CatalogResponse) []string {
+	var out []string
+	if len(catalog.Services) == 0 {
+		out = append(out, "catalog does not contain any service")
+	}
+
+	ids := map[string]struct{}{}
+	uniqueID := func(kind, id string) {
+		if _, found := ids[id]; found {
+			out = append(out, fmt.Sprintf("%s ID %q is not unique", kind, id))
+		}
+		ids[id] = struct{}{}
+	}
+
+	for _, svc := range catalog.Services {
+		if svc.ID == "" || svc.Description == "" {
+			out = append(out, fmt.Sprintf("service %q does not have required id or description", svc.Name))
+		}
+		if !cliFriendlyName.MatchString(svc.Name) {
+			out = append(out, fmt.Sprintf("service name %q is not CLI-friendly", svc.Name))
+		}
+		if len(svc.Plans) == 0 {
+			out = append(out, fmt.Sprintf("service %q does not have any plan", svc.Name))
+		}
+		uniqueID("service", svc.ID)
+
+		planNames := map[string]struct{}{}
+		for _, p := range svc.Plans {
+			if p.ID == "" || p.Description == "" {
+				out = append(out, fmt.Sprintf("plan %q of service %q does not have required id or description", p.Name, svc.Name))
+			}
+			if !cliFriendlyName.MatchString(p.Name) {
+				out = append(out, fmt.Sprintf("plan name %q of service %q is not CLI-friendly", p.Name, svc.Name))
+			}
+			if _, found := planNames[p.Name]; found {
+				out = append(out, fmt.Sprintf("plan name %q of service %q is not unique", p.Name, svc.Name))
+			}
+			planNames[p.Name] = struct{}{}
+			uniqueID("plan", p.ID)
+		}
+	}
+
+	return out
+}
+
+func (s *osbContractSuite) selectServiceAndPlan(catalog osb.CatalogResponse) error {
+	for _, svc := range catalog.Services {
+		if s.serviceID != "" && svc.ID != s.serviceID {
+			continue
+		}
+		if s.serviceID == "" && !svc.Bindable {
+			continue
+		}
+
+		for _, p := range svc.Plans {
+			if s.planID == "" || p.ID == s.planID {
+				s.serviceID, s.planID = svc.ID, p.ID
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("service %q with plan %q not found in the catalog", s.serviceID, s.planID)
+}
+
+func (s *osbContractSuite) checkInvalidRequests(ctx context.Context, _ time.Duration) error {
+	checks := []struct {
+		name     string
+		method   string
+		path     string
+		body     interface{}
+		expected []int
+	}{
+		{
+			name:     "provision with malformed body",
+			method:   http.MethodPut,
+			path:     s.instancePath(uuid.NewV4().String()) + "?accepts_incomplete=true",
+			body:     json.RawMessage(`{"service_id": 1}`),
+			expected: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+		},
+		{
+			name:     "provision of unknown service",
+			method:   http.MethodPut,
+			path:     s.instancePath(uuid.NewV4().String()) + "?accepts_incomplete=true",
+			body:     s.provisionRequest(uuid.NewV4().String(), uuid.NewV4().String(), nil),
+			expected: []int{http.StatusBadRequest, http.StatusUnprocessableEntity},
+		},
+		{
+			name:     "deprovision of unknown instance",
+			method:   http.MethodDelete,
+			path:     s.instancePath(uuid.NewV4().String()) + s.idsQuery() + "&accepts_incomplete=true",
+			expected: []int{http.StatusGone},
+		},
+		{
+			name:     "unbind of unknown binding",
+			method:   http.MethodDelete,
+			path:     s.bindingPath(uuid.NewV4().String(), uuid.NewV4().String()) + s.idsQuery(),
+			expected: []int{http.StatusGone},
+		},
+	}
+
+	var violations []string
+	for _, c := range checks {
+		resp, err := s.client.Do(ctx, c.method, c.path, c.body)
+		if err != nil {
+			return err
+		}
+		if err := resp.ExpectStatus(c.expected...); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %v", c.name, err))
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("invalid requests not rejected properly:\n%s", strings.Join(violations, "\n"))
+	}
+	return nil
+}
+
+func (s *osbContractSuite) checkProvision(ctx context.Context, timeout time.Duration) error {
+	path := s.instancePath(s.instanceID) + "?accepts_incomplete=true"
+	body := s.provisionRequest(s.serviceID, s.planID, s.parameters)
+
+	resp, err := s.client.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusCreated, http.StatusAccepted); err != nil {
+		return errors.Wrap(err, "while provisioning")
+	}
+	s.provisioned = true
+
+	if err := s.waitForOperation(ctx, resp, timeout); err != nil {
+		return errors.Wrap(err, "while waiting for provisioning")
+	}
+
+	// identical request must be idempotent
+	resp, err = s.client.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusOK); err != nil {
+		return errors.Wrap(err, "while repeating identical provision request")
+	}
+
+	if !s.cfg.CheckConflicts {
+		return nil
+	}
+
+	conflicting := s.provisionRequest(s.serviceID, s.planID, map[string]interface{}{"conflicting": uuid.NewV4().String()})
+	resp, err = s.client.Do(ctx, http.MethodPut, path, conflicting)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.ExpectStatus(http.StatusConflict), "while sending conflicting provision request")
+}
+
+func (s *osbContractSuite) checkUpdate(ctx context.Context, timeout time.Duration) error {
+	resp, err := s.client.Do(ctx, http.MethodPatch, s.instancePath(s.instanceID)+"?accepts_incomplete=true", osb.UpdateRequest{
+		ServiceID: s.serviceID,
+		PlanID:    s.cfg.UpdatePlanID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusOK, http.StatusAccepted); err != nil {
+		return errors.Wrap(err, "while updating")
+	}
+	s.planID = s.cfg.UpdatePlanID
+
+	return errors.Wrap(s.waitForOperation(ctx, resp, timeout), "while waiting for update")
+}
+
+func (s *osbContractSuite) checkBind(ctx context.Context, _ time.Duration) error {
+	path := s.bindingPath(s.instanceID, s.bindingID)
+	body := osb.BindRequest{ServiceID: s.serviceID, PlanID: s.planID}
+
+	resp, err := s.client.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusCreated); err != nil {
+		return errors.Wrap(err, "while binding")
+	}
+	var binding osb.BindResponse
+	if err := resp.Decode(&binding); err != nil {
+		return err
+	}
+
+	// identical request must be idempotent
+	resp, err = s.client.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusOK); err != nil {
+		return errors.Wrap(err, "while repeating identical bind request")
+	}
+
+	if !s.cfg.CheckConflicts {
+		return nil
+	}
+
+	body.Parameters = map[string]interface{}{"conflicting": uuid.NewV4().String()}
+	resp, err = s.client.Do(ctx, http.MethodPut, path, body)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.ExpectStatus(http.StatusConflict), "while sending conflicting bind request")
+}
+
+func (s *osbContractSuite) checkUnbind(ctx context.Context, _ time.Duration) error {
+	path := s.bindingPath(s.instanceID, s.bindingID) + s.idsQuery()
+
+	resp, err := s.client.Do(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusOK); err != nil {
+		return errors.Wrap(err, "while unbinding")
+	}
+
+	resp, err = s.client.Do(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.ExpectStatus(http.StatusGone), "while repeating unbind request")
+}
+
+func (s *osbContractSuite) checkDeprovision(ctx context.Context, timeout time.Duration) error {
+	if err := s.deprovision(ctx); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(ctx, http.MethodDelete, s.instancePath(s.instanceID)+s.idsQuery()+"&accepts_incomplete=true", nil)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(resp.ExpectStatus(http.StatusGone), "while repeating deprovision request")
+}
+
+func (s *osbContractSuite) deprovision(ctx context.Context) error {
+	resp, err := s.client.Do(ctx, http.MethodDelete, s.instancePath(s.instanceID)+s.idsQuery()+"&accepts_incomplete=true", nil)
+	if err != nil {
+		return err
+	}
+	if err := resp.ExpectStatus(http.StatusOK, http.StatusAccepted); err != nil {
+		return errors.Wrap(err, "while deprovisioning")
+	}
+
+	if err := s.waitForOperation(ctx, resp, s.cfg.MaxPollingDuration); err != nil {
+		return errors.Wrap(err, "while waiting for deprovisioning")
+	}
+	s.provisioned = false
+	return nil
+}
+
+// waitForOperation polls the last operation endpoint when the broker accepted the request asynchronously.
+// The 410 Gone response is treated as success, which is the expected response for the finished deprovisioning.
+func (s *osbContractSuite) waitForOperation(ctx context.Context, accepted *osb.Response, timeout time.Duration) error {
+	if accepted.StatusCode != http.StatusAccepted {
+		return nil
+	}
+
+	var op osb.OperationResponse
+	if err := accepted.Decode(&op); err != nil {
+		return err
+	}
+
+	path := s.instancePath(s.instanceID) + "/last_operation?" + s.idsQuery()[1:]
+	if op.Operation != "" {
+		path += "&operation=" + op.Operation
+	}
+
+	ticker := time.NewTicker(s.cfg.PollingInterval)
+	defer ticker.Stop()
+	timeoutCh := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("polling last operation interrupted: %v", ctx.Err())
+		case <-timeoutCh:
+			return errors.Errorf("operation not finished in given timeout %v", timeout)
+		case <-ticker.C:
+		}
+
+		resp, err := s.client.Do(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusGone {
+			return nil
+		}
+		if err := resp.ExpectStatus(http.StatusOK); err != nil {
+			return errors.Wrap(err, "while polling last operation")
+		}
+
+		var last osb.LastOperationResponse
+		if err := resp.Decode(&last); err != nil {
+			return err
+		}
+		switch last.State {
+		case osb.StateSucceeded:
+			return nil
+		case osb.StateFailed:
+			return errors.Errorf("operation failed: %s", last.Description)
+		case osb.StateInProgress:
+		default:
+			return errors.Errorf("unknown operation state %q", last.State)
+		}
+	}
+}
+
+func (s *osbContractSuite) provisionRequest(serviceID, planID string, params map[string]interface{}) osb.ProvisionRequest {
+	return osb.ProvisionRequest{
+		ServiceID:        serviceID,
+		PlanID:           planID,
+		OrganizationGUID: "service-catalog-tester",
+		SpaceGUID:        "service-catalog-tester",
+		Parameters:       params,
+	}
+}
+
+func (s *osbContractSuite) instancePath(instanceID string) string {
+	return fmt.Sprintf("/v2/service_instances/%s", instanceID)
+}
+
+func (s *osbContractSuite) bindingPath(instanceID, bindingID string) string {
+	return fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s", instanceID, bindingID)
+}
+
+func (s *osbContractSuite) idsQuery() string {
+	return fmt.Sprintf("?service_id=%s&plan_id=%s", s.serviceID, s.planID)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb"
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
+	"github.com/sirupsen/logrus"
+)
+
+func TestOSBContractTestExecute(t *testing.T) {
+	tests := map[string]struct {
+		brokerCfg fakebroker.Config
+		// wrap modifies the broker handler to break the contract
+		wrap func(http.Handler) http.Handler
+		// expErr is the part of the returned error, nil error is expected when empty
+		expErr string
+	}{
+		"synchronous broker": {},
+		"asynchronous broker": {
+			brokerCfg: fakebroker.Config{AsyncOperations: true, OperationDuration: 50 * time.Millisecond},
+		},
+		"broker does not require API version header": {
+			wrap: func(h http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if req.Header.Get(osb.APIVersionHeader) == "" {
+						req.Header.Set(osb.APIVersionHeader, "2.13")
+					}
+					h.ServeHTTP(w, req)
+				})
+			},
+			expErr: "while checking API version header",
+		},
+		"broker fails requests": {
+			brokerCfg: fakebroker.Config{FailureRate: 1},
+			expErr:    "while checking invalid requests",
+		},
+		"broker fails operations": {
+			brokerCfg: fakebroker.Config{AsyncOperations: true, OperationFailureRate: 1},
+			expErr:    "operation failed",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := logrus.New()
+			log.Out = ioutil.Discard
+			var handler http.Handler = fakebroker.New(tc.brokerCfg, log)
+			if tc.wrap != nil {
+				handler = tc.wrap(handler)
+			}
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			test := NewOSBContractTest(OSBContractTestConfig{
+				BrokerURL:          srv.URL,
+				UpdatePlanID:       fakeBrokerPlanID(t, srv.URL, fakebroker.PlanStandard),
+				CheckConflicts:     true,
+				PollingInterval:    10 * time.Millisecond,
+				MaxPollingDuration: 5 * time.Second,
+				RequestTimeout:     5 * time.Second,
+			})
+
+			err := test.Execute(context.Background())
+
+			switch {
+			case tc.expErr == "" && err != nil:
+				t.Errorf("got error %v, expected nil", err)
+			case tc.expErr != "" && err == nil:
+				t.Errorf("got nil error, expected error containing %q", tc.expErr)
+			case tc.expErr != "" && !strings.Contains(err.Error(), tc.expErr):
+				t.Errorf("got error %v, expected error containing %q", err, tc.expErr)
+			}
+		})
+	}
+}
+
+// fakeBrokerPlanID returns the ID of the fake broker plan with given name
+func fakeBrokerPlanID(t *testing.T, url, planName string) string {
+	t.Helper()
+
+	resp, err := osb.NewClient(osb.ClientConfig{URL: url, Timeout: 5 * time.Second}).Do(context.Background(), http.MethodGet, "/v2/catalog", nil)
+	if err != nil {
+		t.Fatalf("cannot get catalog: %v", err)
+	}
+	var catalog osb.CatalogResponse
+	if err := resp.Decode(&catalog); err != nil {
+		t.Fatalf("cannot decode catalog: %v", err)
+	}
+
+	for _, svc := range catalog.Services {
+		for _, p := range svc.Plans {
+			if p.Name == planName {
+				return p.ID
+			}
+		}
+	}
+	t.Fatalf("plan %q not found in the catalog", planName)
+	return ""
+}
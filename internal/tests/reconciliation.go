@@ -0,0 +1,292 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	scTypes "github.com/kubernetes-incubator/service-catalog/pkg/apis/servicecatalog/v1beta1"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Phases of the Service Catalog reconciliation
+const (
+	// phaseAdmission is the time of the create call to the Service Catalog API server
+	phaseAdmission = "admission"
+	// phasePickup is the time until the controller-manager started processing the resource
+	phasePickup = "pickup"
+	// phaseBroker is the time of the broker call, until the broker responded with the result or accepted the async operation
+	phaseBroker = "broker"
+	// phaseAsyncPolling is the time of polling the last operation of the broker, zero for synchronous brokers
+	phaseAsyncPolling = "async_polling"
+)
+
+// Kinds of resources for which the reconciliation is measured
+const (
+	kindServiceInstance = "ServiceInstance"
+	kindServiceBinding  = "ServiceBinding"
+)
+
+// LatencyObserver records measured latency in seconds with given label values
+type LatencyObserver interface {
+	Observe(value float64, labelValues ...string)
+}
+
+// reconciliationReasons are the reasons of the Ready condition which mark the phases of the reconciliation
+type reconciliationReasons struct {
+	inFlight string
+	async    string
+	ready    string
+}
+
+var reasonsByKind = map[string]reconciliationReasons{
+	kindServiceInstance: {inFlight: "ProvisionRequestInFlight", async: "Provisioning", ready: "ProvisionedSuccessfully"},
+	kindServiceBinding:  {inFlight: "BindingRequestInFlight", async: "Binding", ready: "InjectedBindResult"},
+}
+
+// reconciliationRecorder watches ServiceInstances and ServiceBindings in the test namespace
+// and records when each reason of the Ready condition was observed for the first time
+type reconciliationRecorder struct {
+	mu        sync.Mutex
+	timelines map[string]*reconciliationTimeline
+	watchers  []watch.Interface
+	wg        sync.WaitGroup
+}
+
+type reconciliationTimeline struct {
+	requested time.Time
+	admitted  time.Time
+	// reasons holds the local time when the reason was observed by the watch
+	reasons map[string]time.Time
+	// readyTransition is the LastTransitionTime of the Ready condition set to True, used when the watch missed it
+	readyTransition time.Time
+}
+
+type reconciliationPhases struct {
+	admission    time.Duration
+	pickup       time.Duration
+	broker       time.Duration
+	asyncPolling time.Duration
+}
+
+// startReconciliationRecorder starts watching ServiceInstances and ServiceBindings in the test namespace.
+// The recorder must be started before the resources are created and stopped when they are not needed anymore.
+func (ts *testSuite) startReconciliationRecorder() (*reconciliationRecorder, error) {
+	r := &reconciliationRecorder{timelines: map[string]*reconciliationTimeline{}}
+
+	siWatcher, err := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace).Watch(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "while watching ServiceInstances")
+	}
+	bindingWatcher, err := ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace).Watch(metav1.ListOptions{})
+	if err != nil {
+		siWatcher.Stop()
+		return nil, errors.Wrap(err, "while watching ServiceBindings")
+	}
+	r.watchers = []watch.Interface{siWatcher, bindingWatcher}
+
+	r.wg.Add(len(r.watchers))
+	for _, w := range r.watchers {
+		go r.consume(w)
+	}
+
+	return r, nil
+}
+
+func (r *reconciliationRecorder) consume(w watch.Interface) {
+	defer r.wg.Done()
+
+	for ev := range w.ResultChan() {
+		if ev.Type != watch.Added && ev.Type != watch.Modified {
+			continue
+		}
+
+		switch obj := ev.Object.(type) {
+		case *scTypes.ServiceInstance:
+			for _, cond := range obj.Status.Conditions {
+				if cond.Type == scTypes.ServiceInstanceConditionReady {
+					r.observe(kindServiceInstance, obj.Name, cond.Reason, cond.Status, cond.LastTransitionTime)
+				}
+			}
+		case *scTypes.ServiceBinding:
+			for _, cond := range obj.Status.Conditions {
+				if cond.Type == scTypes.ServiceBindingConditionReady {
+					r.observe(kindServiceBinding, obj.Name, cond.Reason, cond.Status, cond.LastTransitionTime)
+				}
+			}
+		}
+	}
+}
+
+// stop stops the watches and waits until all received events are recorded
+func (r *reconciliationRecorder) stop() {
+	if r == nil {
+		return
+	}
+	for _, w := range r.watchers {
+		w.Stop()
+	}
+	r.wg.Wait()
+}
+
+// requested marks the time just before the resource create call. It is a no-op for nil recorder.
+func (r *reconciliationRecorder) requested(kind, name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timeline(kind, name).requested = time.Now()
+}
+
+// admitted marks the time when the create call returned. It is a no-op for nil recorder.
+func (r *reconciliationRecorder) admitted(kind, name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timeline(kind, name).admitted = time.Now()
+}
+
+func (r *reconciliationRecorder) observe(kind, name, reason string, status scTypes.ConditionStatus, lastTransition metav1.Time) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tl := r.timeline(kind, name)
+	if _, seen := tl.reasons[reason]; !seen {
+		tl.reasons[reason] = now
+	}
+	if status == scTypes.ConditionTrue && tl.readyTransition.IsZero() {
+		tl.readyTransition = lastTransition.Time
+	}
+}
+
+func (r *reconciliationRecorder) timeline(kind, name string) *reconciliationTimeline {
+	key := kind + "/" + name
+	tl, found := r.timelines[key]
+	if !found {
+		tl = &reconciliationTimeline{reasons: map[string]time.Time{}}
+		r.timelines[key] = tl
+	}
+	return tl
+}
+
+// report records the reconciliation phases of given resource as the step detail and observes them in given observer.
+// When the resource did not get ready, the observed reasons are recorded instead, so it is visible where it got stuck.
+func (r *reconciliationRecorder) report(ctx context.Context, observer LatencyObserver, kind, name string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	detailName := fmt.Sprintf("%s %s reconciliation", kind, name)
+	tl, found := r.timelines[kind+"/"+name]
+	if !found || tl.requested.IsZero() {
+		return
+	}
+
+	phases, err := tl.phases(reasonsByKind[kind])
+	if err != nil {
+		runner.RecordDetail(ctx, detailName, fmt.Sprintf("not measured, %v; observed reasons: %s", err, tl.observedReasons()))
+		return
+	}
+
+	runner.RecordDetail(ctx, detailName, phases.String())
+	if observer == nil {
+		return
+	}
+	observer.Observe(phases.admission.Seconds(), kind, phaseAdmission)
+	observer.Observe(phases.pickup.Seconds(), kind, phasePickup)
+	observer.Observe(phases.broker.Seconds(), kind, phaseBroker)
+	observer.Observe(phases.asyncPolling.Seconds(), kind, phaseAsyncPolling)
+}
+
+// phases splits the time between the create call and the ready resource into the reconciliation phases
+func (tl *reconciliationTimeline) phases(reasons reconciliationReasons) (reconciliationPhases, error) {
+	if tl.admitted.IsZero() {
+		return reconciliationPhases{}, errors.New("create call did not return")
+	}
+
+	readyAt, ok := tl.reasons[reasons.ready]
+	if !ok {
+		// LastTransitionTime has the second precision, but is better than nothing when the watch missed the event
+		readyAt = tl.readyTransition
+	}
+	if readyAt.IsZero() {
+		return reconciliationPhases{}, fmt.Errorf("reason %s not observed", reasons.ready)
+	}
+
+	pickedUpAt, ok := tl.reasons[reasons.inFlight]
+	if !ok {
+		pickedUpAt = tl.firstObserved()
+	}
+	brokerDoneAt := readyAt
+	var asyncPolling time.Duration
+	if asyncAt, ok := tl.reasons[reasons.async]; ok {
+		brokerDoneAt = asyncAt
+		asyncPolling = nonNegative(readyAt.Sub(asyncAt))
+	}
+
+	return reconciliationPhases{
+		admission:    nonNegative(tl.admitted.Sub(tl.requested)),
+		pickup:       nonNegative(pickedUpAt.Sub(tl.admitted)),
+		broker:       nonNegative(brokerDoneAt.Sub(pickedUpAt)),
+		asyncPolling: asyncPolling,
+	}, nil
+}
+
+func (tl *reconciliationTimeline) firstObserved() time.Time {
+	var first time.Time
+	for _, at := range tl.reasons {
+		if first.IsZero() || at.Before(first) {
+			first = at
+		}
+	}
+	return first
+}
+
+// observedReasons returns reasons ordered by the time they were observed, relative to the create call
+func (tl *reconciliationTimeline) observedReasons() string {
+	if len(tl.reasons) == 0 {
+		return "none"
+	}
+
+	var observed []string
+	for reason := range tl.reasons {
+		observed = append(observed, reason)
+	}
+	sort.Slice(observed, func(i, j int) bool {
+		return tl.reasons[observed[i]].Before(tl.reasons[observed[j]])
+	})
+	for i, reason := range observed {
+		observed[i] = fmt.Sprintf("%s after %v", reason, tl.reasons[reason].Sub(tl.requested).Round(time.Millisecond))
+	}
+	return strings.Join(observed, ", ")
+}
+
+func (p reconciliationPhases) String() string {
+	return fmt.Sprintf("%s: %v, %s: %v, %s: %v, %s: %v",
+		phaseAdmission, p.admission.Round(time.Millisecond),
+		phasePickup, p.pickup.Round(time.Millisecond),
+		phaseBroker, p.broker.Round(time.Millisecond),
+		phaseAsyncPolling, p.asyncPolling.Round(time.Millisecond))
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
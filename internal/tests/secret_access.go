@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"github.com/pkg/errors"
+	rbacTypes "k8s.io/api/rbac/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// secretReaderRoleBindingName is the name of the RoleBinding which grants the tester read access to Secrets in the test namespace
+const secretReaderRoleBindingName = "service-catalog-tester-secret-reader"
+
+// SecretAccessConfig defines how the tester gets read access to binding Secrets. The tester is not allowed to read
+// Secrets in the whole cluster, so the ClusterRole with the access is bound only in namespaces created by tests.
+type SecretAccessConfig struct {
+	// ClusterRole is the ClusterRole which allows to read Secrets. The access is not granted when empty,
+	// e.g. when the tester is executed with the kubeconfig which already has the access.
+	ClusterRole             string `envconfig:"optional"`
+	ServiceAccountName      string `envconfig:"optional"`
+	ServiceAccountNamespace string `envconfig:"optional"`
+}
+
+// grantSecretAccess binds the ClusterRole which allows to read Secrets to the tester ServiceAccount in given namespace
+func grantSecretAccess(k8sCli kubernetes.Interface, cfg SecretAccessConfig, namespace string) error {
+	if cfg.ClusterRole == "" {
+		return nil
+	}
+	if cfg.ServiceAccountName == "" || cfg.ServiceAccountNamespace == "" {
+		return errors.New("ServiceAccount name and namespace are required to bind the ClusterRole")
+	}
+
+	_, err := k8sCli.RbacV1().RoleBindings(namespace).Create(&rbacTypes.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: secretReaderRoleBindingName,
+		},
+		Subjects: []rbacTypes.Subject{
+			{
+				Kind:      rbacTypes.ServiceAccountKind,
+				Name:      cfg.ServiceAccountName,
+				Namespace: cfg.ServiceAccountNamespace,
+			},
+		},
+		RoleRef: rbacTypes.RoleRef{
+			APIGroup: rbacTypes.GroupName,
+			Kind:     "ClusterRole",
+			Name:     cfg.ClusterRole,
+		},
+	})
+	if err != nil && !apiErrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "while binding ClusterRole %s in namespace %s", cfg.ClusterRole, namespace)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package tests
+
+import (
+	"context"
+	"time"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/probe"
+)
+
+// probeService checks with given probe that the provisioned service is usable with the ServiceBinding credentials
+func (ts *testSuite) probeService(ctx context.Context, serviceProbe probe.Probe, timeout time.Duration) error {
+	secret, err := ts.bindingSecret()
+	if err != nil {
+		return err
+	}
+
+	creds := probe.Credentials{}
+	for k, v := range secret.Data {
+		creds[k] = string(v)
+	}
+
+	return repeatUntilTimeout(ctx, func() error {
+		return serviceProbe.Check(ctx, creds)
+	}, timeout)
+}
@@ -0,0 +1,341 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	scClient "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
+	bucClient "github.com/kyma-project/kyma/components/binding-usage-controller/pkg/client/clientset/versioned"
+
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/labels"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	k8sCoreTypes "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+const (
+	// SecretChecksumAnnotation holds the checksum of the binding Secret recorded on the tester Deployment
+	// when the pool member was created
+	SecretChecksumAnnotation = "service-catalog-tester.kyma-project.io/secret-checksum"
+)
+
+// SoakTest keeps a pool of long-lived ServiceInstances and ServiceBindings in a dedicated namespace
+// and verifies in each run that none of them degraded:
+// - missing pool members are created, the complete ones are never removed
+// - members left incomplete, e.g. when the creation failed or the tester was restarted, are removed and created again
+// - ServiceInstances and ServiceBindings are still ready
+// - binding Secrets are present, not empty and their content did not change since the creation
+// - Secret values are still injected into the tester Deployment by the ServiceBindingUsage
+//
+// Degraded members are kept for investigation. Delete the soak namespace to reset the pool.
+type SoakTest struct {
+	cfg          SoakTestConfig
+	tester       TesterConfig
+	secretAccess SecretAccessConfig
+	k8sClientCfg *restclient.Config
+	log          logrus.FieldLogger
+}
+
+// SoakTestConfig holds possible configuration for test
+type SoakTestConfig struct {
+	Enabled   bool   `envconfig:"default=false"`
+	Namespace string `envconfig:"default=service-catalog-soak"`
+	PoolSize  int    `envconfig:"default=3"`
+	// VerifyTimeout is the time in which the injected envs are expected to match the Secret
+	VerifyTimeout time.Duration `envconfig:"default=1m"`
+	Test          runner.ScheduleConfig
+}
+
+// NewSoakTest returns new instance of SoakTest
+func NewSoakTest(cfg SoakTestConfig, tester TesterConfig, secretAccess SecretAccessConfig, k8sClientCfg *restclient.Config, log logrus.FieldLogger) (*SoakTest, error) {
+	if cfg.PoolSize < 1 {
+		return nil, errors.Errorf("pool size must be at least 1, got %d", cfg.PoolSize)
+	}
+
+	return &SoakTest{
+		cfg:          cfg,
+		tester:       tester,
+		secretAccess: secretAccess,
+		k8sClientCfg: k8sClientCfg,
+		log:          log.WithField("service", "test:soak"),
+	}, nil
+}
+
+// Execute executes the soak test
+func (t *SoakTest) Execute(ctx context.Context) error {
+	runner.StartStep(ctx, "creating clients")
+	members, err := t.poolMembers()
+	if err != nil {
+		return errors.Wrap(err, "while creating clients")
+	}
+
+	runner.StartStep(ctx, "ensuring soak namespace")
+	if err := t.ensureNamespace(members[0].k8sCli); err != nil {
+		return errors.Wrapf(err, "while ensuring namespace %s", t.cfg.Namespace)
+	}
+
+	runner.StartStep(ctx, "granting access to Secrets")
+	if err := grantSecretAccess(members[0].k8sCli, t.secretAccess, t.cfg.Namespace); err != nil {
+		return errors.Wrap(err, "while granting access to Secrets")
+	}
+
+	var degraded, notCreated []error
+	for _, ts := range members {
+		runner.StartStep(ctx, fmt.Sprintf("checking pool member %s", ts.serviceInstanceName))
+		complete, err := t.memberComplete(ts)
+		if err != nil {
+			notCreated = append(notCreated, errors.Wrapf(err, "while checking pool member %s", ts.serviceInstanceName))
+			continue
+		}
+
+		if !complete {
+			t.log.WithField("ID", runner.RunID(ctx)).Infof("Creating pool member %s", ts.serviceInstanceName)
+			if err := t.recreateMember(ctx, ts); err != nil {
+				notCreated = append(notCreated, errors.Wrapf(err, "while creating pool member %s", ts.serviceInstanceName))
+			}
+			continue
+		}
+
+		if err := t.verifyMember(ctx, ts); err != nil {
+			degraded = append(degraded, errors.Wrapf(err, "pool member %s degraded", ts.serviceInstanceName))
+		}
+	}
+
+	if len(degraded) > 0 || len(notCreated) > 0 {
+		return appendErr(errors.Errorf("%d of %d pool members in namespace %s degraded, %d not created", len(degraded), len(members), t.cfg.Namespace, len(notCreated)), append(notCreated, degraded...)...)
+	}
+
+	return nil
+}
+
+// Name returns the name of the stress test
+func (t *SoakTest) Name() string {
+	return "Service Catalog Soak"
+}
+
+// poolMembers returns the test suite for each member of the pool. Names are stable, so the members
+// created by previous runs, also before the tester restart, are found.
+func (t *SoakTest) poolMembers() ([]*testSuite, error) {
+	k8sCli, err := kubernetes.NewForConfig(t.k8sClientCfg)
+	if err != nil {
+		return nil, err
+	}
+	scCli, err := scClient.NewForConfig(t.k8sClientCfg)
+	if err != nil {
+		return nil, err
+	}
+	bucCli, err := bucClient.NewForConfig(t.k8sClientCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*testSuite
+	for i := 0; i < t.cfg.PoolSize; i++ {
+		members = append(members, &testSuite{
+			k8sCli: k8sCli,
+			scCli:  scCli,
+			bucCli: bucCli,
+
+			namespace:            t.cfg.Namespace,
+			testerDeploymentName: fmt.Sprintf("soak-env-tester-%d", i),
+
+			serviceInstanceName:     fmt.Sprintf("soak-instance-%d", i),
+			bindingName:             fmt.Sprintf("soak-credential-%d", i),
+			testerDeploymentSvcName: fmt.Sprintf("soak-svc-%d", i),
+			bindingUsageName:        fmt.Sprintf("soak-binding-usage-%d", i),
+
+			tester: t.tester,
+		})
+	}
+	return members, nil
+}
+
+func (t *SoakTest) ensureNamespace(k8sCli kubernetes.Interface) error {
+	_, err := k8sCli.CoreV1().Namespaces().Get(t.cfg.Namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return nil
+	case !apiErrors.IsNotFound(err):
+		return err
+	}
+
+	_, err = k8sCli.CoreV1().Namespaces().Create(&k8sCoreTypes.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: t.cfg.Namespace,
+			Labels: map[string]string{
+				"env":                "true",
+				labels.SoakNamespace: "true",
+			},
+		},
+	})
+	return err
+}
+
+// memberComplete returns true when the Secret checksum is recorded on the tester Deployment,
+// which is the last step of the pool member creation
+func (t *SoakTest) memberComplete(ts *testSuite) (bool, error) {
+	deploy, err := ts.k8sCli.AppsV1beta1().Deployments(ts.namespace).Get(ts.testerDeploymentName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return deploy.Annotations[SecretChecksumAnnotation] != "", nil
+	case apiErrors.IsNotFound(err):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// recreateMember removes what is left from the incomplete pool member and creates it from scratch
+func (t *SoakTest) recreateMember(ctx context.Context, ts *testSuite) error {
+	runner.StartStep(ctx, "removing incomplete pool member")
+	if err := t.deleteMember(ctx, ts); err != nil {
+		return errors.Wrap(err, "while removing incomplete pool member")
+	}
+
+	err := executeSteps(ctx, timeoutPerStep,
+		step{name: "creating ServiceInstance", fn: ts.createAndWaitForRedisInstance},
+		step{name: "creating ServiceBinding", fn: ts.createAndWaitForRedisServiceBinding},
+		step{name: "creating tester Deployment", fn: ts.createTesterDeploymentAndService},
+		step{name: "creating ServiceBindingUsage", fn: ts.createBindingUsageForTesterDeployment},
+	)
+	if err != nil {
+		return err
+	}
+
+	secret, err := ts.bindingSecret()
+	if err != nil {
+		return err
+	}
+
+	runner.StartStep(ctx, "verifying injected envs")
+	if err := ts.assertInjectedEnvVariable(ctx, "PORT", string(secret.Data["PORT"]), 2*timeoutPerStep); err != nil {
+		return err
+	}
+
+	// the checksum marks the member as complete, so it is recorded as the last step
+	runner.StartStep(ctx, "recording Secret checksum")
+	deploymentClient := ts.k8sCli.AppsV1beta1().Deployments(ts.namespace)
+	deploy, err := deploymentClient.Get(ts.testerDeploymentName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "while getting tester Deployment")
+	}
+	if deploy.Annotations == nil {
+		deploy.Annotations = map[string]string{}
+	}
+	deploy.Annotations[SecretChecksumAnnotation] = secretChecksum(secret)
+	if _, err := deploymentClient.Update(deploy); err != nil {
+		return errors.Wrap(err, "while recording Secret checksum")
+	}
+
+	return nil
+}
+
+// deleteMember deletes all resources of the pool member and waits until they are removed, so they can be created again
+func (t *SoakTest) deleteMember(ctx context.Context, ts *testSuite) error {
+	var (
+		usages      = ts.bucCli.ServicecatalogV1alpha1().ServiceBindingUsages(ts.namespace)
+		deployments = ts.k8sCli.AppsV1beta1().Deployments(ts.namespace)
+		services    = ts.k8sCli.CoreV1().Services(ts.namespace)
+		bindings    = ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace)
+		instances   = ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace)
+	)
+
+	deleteErrs := map[string]error{
+		"ServiceBindingUsage": usages.Delete(ts.bindingUsageName, &metav1.DeleteOptions{}),
+		"Deployment":          deployments.Delete(ts.testerDeploymentName, &metav1.DeleteOptions{}),
+		"Service":             services.Delete(ts.testerDeploymentSvcName, &metav1.DeleteOptions{}),
+		"ServiceBinding":      bindings.Delete(ts.bindingName, &metav1.DeleteOptions{}),
+		"ServiceInstance":     instances.Delete(ts.serviceInstanceName, &metav1.DeleteOptions{}),
+	}
+	for kind, err := range deleteErrs {
+		if err != nil && !apiErrors.IsNotFound(err) {
+			return errors.Wrapf(err, "while deleting %s", kind)
+		}
+	}
+
+	return repeatUntilTimeout(ctx, func() error {
+		if _, err := usages.Get(ts.bindingUsageName, metav1.GetOptions{}); !apiErrors.IsNotFound(err) {
+			return notRemovedErr("ServiceBindingUsage", err)
+		}
+		if _, err := deployments.Get(ts.testerDeploymentName, metav1.GetOptions{}); !apiErrors.IsNotFound(err) {
+			return notRemovedErr("Deployment", err)
+		}
+		if _, err := services.Get(ts.testerDeploymentSvcName, metav1.GetOptions{}); !apiErrors.IsNotFound(err) {
+			return notRemovedErr("Service", err)
+		}
+		if _, err := bindings.Get(ts.bindingName, metav1.GetOptions{}); !apiErrors.IsNotFound(err) {
+			return notRemovedErr("ServiceBinding", err)
+		}
+		if _, err := instances.Get(ts.serviceInstanceName, metav1.GetOptions{}); !apiErrors.IsNotFound(err) {
+			return notRemovedErr("ServiceInstance", err)
+		}
+		return nil
+	}, timeoutPerStep)
+}
+
+func notRemovedErr(kind string, getErr error) error {
+	if getErr != nil {
+		return errors.Wrapf(getErr, "while checking %s removal", kind)
+	}
+	return errors.Errorf("%s not removed yet", kind)
+}
+
+func (t *SoakTest) verifyMember(ctx context.Context, ts *testSuite) error {
+	si, err := ts.scCli.ServicecatalogV1beta1().ServiceInstances(ts.namespace).Get(ts.serviceInstanceName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "while getting ServiceInstance")
+	}
+	if ready, err := instanceReady(si); err != nil || !ready {
+		return fmt.Errorf("ServiceInstance is not ready, last condition: %s", lastInstanceCondition(si.Status.Conditions))
+	}
+
+	b, err := ts.scCli.ServicecatalogV1beta1().ServiceBindings(ts.namespace).Get(ts.bindingName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "while getting ServiceBinding")
+	}
+	if ready, err := bindingReady(b); err != nil || !ready {
+		return fmt.Errorf("ServiceBinding is not ready, last condition: %s", lastBindingCondition(b.Status.Conditions))
+	}
+
+	secret, err := ts.bindingSecret()
+	if err != nil {
+		return err
+	}
+	deploy, err := ts.k8sCli.AppsV1beta1().Deployments(ts.namespace).Get(ts.testerDeploymentName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "while getting tester Deployment")
+	}
+	if recorded, current := deploy.Annotations[SecretChecksumAnnotation], secretChecksum(secret); recorded != current {
+		return fmt.Errorf("content of Secret %s changed since the creation [recorded checksum: %q, current: %q]", secret.Name, recorded, current)
+	}
+
+	if err := ts.assertInjectedEnvVariable(ctx, "PORT", string(secret.Data["PORT"]), t.cfg.VerifyTimeout); err != nil {
+		return errors.Wrap(err, "while checking that envs are injected")
+	}
+
+	return nil
+}
+
+// secretChecksum returns the checksum of the Secret data which does not depend on the order of keys
+func secretChecksum(secret *k8sCoreTypes.Secret) string {
+	var keys []string
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%x;", k, secret.Data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
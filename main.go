@@ -3,21 +3,31 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"os"
 	"time"
 
+	scClientset "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
 	"github.com/kyma-incubator/service-catalog-tester/internal/collector"
+	"github.com/kyma-incubator/service-catalog-tester/internal/diagnostics"
+	"github.com/kyma-incubator/service-catalog-tester/internal/janitor"
+	"github.com/kyma-incubator/service-catalog-tester/internal/metrics"
 	"github.com/kyma-incubator/service-catalog-tester/internal/monitoring"
 	"github.com/kyma-incubator/service-catalog-tester/internal/notifier"
+	"github.com/kyma-incubator/service-catalog-tester/internal/osb/fakebroker"
 	"github.com/kyma-incubator/service-catalog-tester/internal/platform/logger"
 	"github.com/kyma-incubator/service-catalog-tester/internal/platform/signal"
+	"github.com/kyma-incubator/service-catalog-tester/internal/probe"
 	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
 	"github.com/kyma-incubator/service-catalog-tester/internal/tests"
+	bucClientset "github.com/kyma-project/kyma/components/binding-usage-controller/pkg/client/clientset/versioned"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/vrischmann/envconfig"
 	"k8s.io/client-go/informers"
 	k8sClientset "k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -27,20 +37,58 @@ const informerResyncPeriod = 30 * time.Minute
 
 // Config holds application configuration
 type Config struct {
-	Logger                     logger.Config
-	Port                       int    `envconfig:"default=8080"`
-	KubeconfigPath             string `envconfig:"optional"`
-	SlackClient                notifier.SlackClientConfig
-	ClusterName                string
-	ObservableDeployments      collector.DeploymentConfig
+	Logger                 logger.Config
+	Port                   int    `envconfig:"default=8080"`
+	KubeconfigPath         string `envconfig:"optional"`
+	SlackClient            notifier.SlackClientConfig
+	ClusterName            string
+	ObservableDeployments  collector.DeploymentConfig
+	AlertPolicy            runner.AlertPolicyConfig
+	Runner                 runner.Config
+	NamespaceJanitor       janitor.NamespaceJanitorConfig
+	BrokerMonitor          monitoring.BrokerMonitorConfig
+	FailedResourceDetector monitoring.FailedResourceDetectorConfig
+}
+
+// TestsConfig holds configuration of the executed tests.
+// It is shared between the stress loop and the one-shot mode.
+type TestsConfig struct {
+	Diagnostics                diagnostics.Config
+	FakeBroker                 tests.FakeBrokerConfig
+	BindingSecret              tests.BindingSecretConfig
+	SecretAccess               tests.SecretAccessConfig
+	ServiceProbe               probe.Config
+	Tester                     tests.TesterConfig
 	E2EServiceCatalogHappyPath tests.E2EServiceCatalogHappyPathTestConfig
+	E2ENamespacedBroker        tests.E2ENamespacedBrokerTestConfig
+	OSBContract                tests.OSBContractTestConfig
+	EtcdStress                 tests.EtcdStressTestConfig
+	Load                       tests.LoadTestConfig
+	Soak                       tests.SoakTestConfig
+	InstanceUpdate             tests.InstanceUpdateTestConfig
+	NegativePath               tests.NegativePathTestConfig
+	BindingUsage               tests.BindingUsageTestConfig
+	APILatency                 tests.APILatencyTestConfig
 }
 
 func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	if len(os.Args) > 1 && os.Args[1] == runOnceCmd {
+		os.Exit(runOnce(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == fakeBrokerCmd {
+		os.Exit(runFakeBroker())
+	}
+
 	var cfg Config
 	err := envconfig.InitWithPrefix(&cfg, "APP")
 	fatalOnError(err, "while reading configuration from environment variables")
 
+	var testsCfg TestsConfig
+	err = envconfig.InitWithPrefix(&testsCfg, "APP")
+	fatalOnError(err, "while reading tests configuration from environment variables")
+
 	log := logger.New(&cfg.Logger)
 
 	// set up signals so we can handle the first shutdown signal gracefully
@@ -54,6 +102,12 @@ func main() {
 	fatalOnError(err, "while creating k8s clientset")
 	k8sInformersFactory := informers.NewSharedInformerFactoryWithOptions(k8sCli, informerResyncPeriod)
 
+	scCli, err := scClientset.NewForConfig(k8sConfig)
+	fatalOnError(err, "while creating Service Catalog clientset")
+
+	bucCli, err := bucClientset.NewForConfig(k8sConfig)
+	fatalOnError(err, "while creating ServiceBindingUsage clientset")
+
 	// Slack Notifier
 	slackClient := notifier.NewSlackClient(cfg.SlackClient)
 	msgRenderer, err := notifier.NewMessageRenderer()
@@ -67,23 +121,162 @@ func main() {
 
 	watchSvc := monitoring.NewWatcherService(k8sCli.CoreV1(), sNotifier, log)
 	monitor := monitoring.NewPodDetector(k8sInformersFactory.Core().V1().Pods(), watchSvc, log, observableDeploys)
+	var brokerMonitor *monitoring.BrokerMonitor
+	if cfg.BrokerMonitor.Enabled {
+		// created only when enabled, as it registers the Namespace informer in the factory
+		brokerMonitor = monitoring.NewBrokerMonitor(cfg.BrokerMonitor, scCli.ServicecatalogV1beta1(), k8sInformersFactory.Core().V1().Namespaces(), sNotifier, informerResyncPeriod, log)
+	}
+	var failedDetector *monitoring.FailedResourceDetector
+	if cfg.FailedResourceDetector.Enabled {
+		// created only when enabled, as it registers the Namespace informer in the factory
+		failedDetector = monitoring.NewFailedResourceDetector(cfg.FailedResourceDetector, scCli.ServicecatalogV1beta1(), k8sInformersFactory.Core().V1().Namespaces(), sNotifier, informerResyncPeriod, log)
+	}
 
 	// Test Runner
-	testRunner := runner.NewStressTestRunner(sNotifier, log)
-	E2EServiceCatalogHappyPath := tests.NewE2EServiceCatalogHappyPathTest(cfg.E2EServiceCatalogHappyPath, k8sConfig)
+	alertPolicy, err := runner.NewAlertPolicy(cfg.AlertPolicy)
+	fatalOnError(err, "while creating alert policy")
+	testRunner := runner.NewStressTestRunner(cfg.Runner, sNotifier, alertPolicy, log)
+	controlHandler := runner.NewControlHandler(testRunner, log)
+
+	// Diagnostics collected on test failures
+	diagCollector := diagnostics.NewCollector(testsCfg.Diagnostics, k8sCli, scCli, log)
+	diagHandler := diagnostics.NewHandler(testsCfg.Diagnostics)
+
+	// Fake broker served by the tester, used by tests when the in-process mode is configured
+	var registrars []routesRegistrar
+	metricsRegistry := metrics.NewRegistry()
+	registrars = append(registrars, controlHandler, diagHandler, metricsRegistry)
+	if testsCfg.FakeBroker.Mode == tests.FakeBrokerModeInProcess {
+		registrars = append(registrars, fakebroker.New(testsCfg.FakeBroker.Broker, log))
+	}
+
+	// Leaked test namespaces and UsageKinds clean-up
+	nsJanitor := janitor.NewNamespaceJanitor(cfg.NamespaceJanitor, k8sCli.CoreV1(), scCli.ServicecatalogV1beta1(), bucCli.ServicecatalogV1alpha1(), sNotifier, log)
 
 	// Start services
 	err = monitor.Start()
 	fatalOnError(err, "while starting resources monitoring")
 
-	go testRunner.Run(stopCh, cfg.E2EServiceCatalogHappyPath.TestThrottle, E2EServiceCatalogHappyPath)
+	if cfg.NamespaceJanitor.Enabled {
+		go nsJanitor.Run(stopCh)
+	}
+
+	if cfg.BrokerMonitor.Enabled {
+		go brokerMonitor.Run(stopCh)
+	}
+
+	if cfg.FailedResourceDetector.Enabled {
+		go failedDetector.Run(stopCh)
+	}
+
+	for _, t := range newConfiguredTests(testsCfg, k8sConfig, diagCollector, metricsRegistry, log) {
+		go func(t runner.ConfiguredTest) {
+			err := testRunner.Run(stopCh, t.Schedule, t.Test)
+			fatalOnError(err, "while running tests")
+		}(t)
+	}
 
 	// Start informers
 	k8sInformersFactory.Start(stopCh)
 	// Wait for cache sync
 	k8sInformersFactory.WaitForCacheSync(stopCh)
 
-	runStatuszHTTPServer(stopCh, fmt.Sprintf(":%d", cfg.Port), log)
+	runHTTPServer(stopCh, fmt.Sprintf(":%d", cfg.Port), log, registrars...)
+}
+
+func newConfiguredTests(cfg TestsConfig, k8sConfig *restclient.Config, diagCollector tests.DiagnosticsCollector, metricsRegistry *metrics.Registry, log logrus.FieldLogger) []runner.ConfiguredTest {
+	reconciliationLatency := metricsRegistry.NewHistogramVec(
+		"service_catalog_tester_reconciliation_phase_seconds",
+		"Duration of the Service Catalog reconciliation phases measured by the E2E test",
+		[]float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		"kind", "phase",
+	)
+
+	configured := []runner.ConfiguredTest{
+		{
+			Test:     tests.NewE2EServiceCatalogHappyPathTest(cfg.E2EServiceCatalogHappyPath, cfg.BindingSecret, cfg.SecretAccess, probe.New(cfg.ServiceProbe), cfg.Tester, reconciliationLatency, k8sConfig, diagCollector),
+			Schedule: cfg.E2EServiceCatalogHappyPath.Test,
+		},
+	}
+
+	if cfg.E2ENamespacedBroker.Enabled {
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     tests.NewE2ENamespacedBrokerTest(cfg.E2ENamespacedBroker, cfg.FakeBroker, k8sConfig, diagCollector),
+			Schedule: cfg.E2ENamespacedBroker.Test,
+		})
+	}
+
+	if cfg.OSBContract.Enabled {
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     tests.NewOSBContractTest(cfg.OSBContract),
+			Schedule: cfg.OSBContract.Test,
+		})
+	}
+
+	if cfg.EtcdStress.Enabled {
+		etcdStressTest, err := tests.NewEtcdStressTest(cfg.EtcdStress, k8sConfig, log)
+		fatalOnError(err, "while creating etcd stress test")
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     etcdStressTest,
+			Schedule: cfg.EtcdStress.Test,
+		})
+	}
+
+	if cfg.Load.Enabled {
+		loadTest, err := tests.NewLoadTest(cfg.Load, k8sConfig, log)
+		fatalOnError(err, "while creating load test")
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     loadTest,
+			Schedule: cfg.Load.Test,
+		})
+	}
+
+	if cfg.Soak.Enabled {
+		soakTest, err := tests.NewSoakTest(cfg.Soak, cfg.Tester, cfg.SecretAccess, k8sConfig, log)
+		fatalOnError(err, "while creating soak test")
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     soakTest,
+			Schedule: cfg.Soak.Test,
+		})
+	}
+
+	if cfg.InstanceUpdate.Enabled {
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     tests.NewInstanceUpdateTest(cfg.InstanceUpdate, cfg.FakeBroker, cfg.SecretAccess, k8sConfig, diagCollector),
+			Schedule: cfg.InstanceUpdate.Test,
+		})
+	}
+
+	if cfg.NegativePath.Enabled {
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     tests.NewNegativePathTest(cfg.NegativePath, cfg.FakeBroker, k8sConfig, diagCollector),
+			Schedule: cfg.NegativePath.Test,
+		})
+	}
+
+	if cfg.BindingUsage.Enabled {
+		bindingUsageTest, err := tests.NewBindingUsageTest(cfg.BindingUsage, cfg.Tester, cfg.SecretAccess, k8sConfig, diagCollector)
+		fatalOnError(err, "while creating binding usage test")
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     bindingUsageTest,
+			Schedule: cfg.BindingUsage.Test,
+		})
+	}
+
+	if cfg.APILatency.Enabled {
+		apiLatency := metricsRegistry.NewHistogramVec(
+			"service_catalog_tester_apiserver_request_seconds",
+			"Duration of the Service Catalog API server requests measured by the API server latency probe",
+			[]float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+			"resource", "verb",
+		)
+		configured = append(configured, runner.ConfiguredTest{
+			Test:     tests.NewAPILatencyTest(cfg.APILatency, apiLatency, k8sConfig, log),
+			Schedule: cfg.APILatency.Test,
+		})
+	}
+
+	return configured
 }
 
 func fatalOnError(err error, context string) {
@@ -92,12 +285,19 @@ func fatalOnError(err error, context string) {
 	}
 }
 
-func runStatuszHTTPServer(stop <-chan struct{}, addr string, log logrus.FieldLogger) {
+type routesRegistrar interface {
+	RegisterRoutes(mux *http.ServeMux)
+}
+
+func runHTTPServer(stop <-chan struct{}, addr string, log logrus.FieldLogger, registrars ...routesRegistrar) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/statusz", func(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "OK")
 	})
+	for _, r := range registrars {
+		r.RegisterRoutes(mux)
+	}
 
 	srv := &http.Server{Addr: addr, Handler: mux}
 
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	scClientset "github.com/kubernetes-incubator/service-catalog/pkg/client/clientset_generated/clientset"
+	"github.com/kyma-incubator/service-catalog-tester/internal/diagnostics"
+	"github.com/kyma-incubator/service-catalog-tester/internal/metrics"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/logger"
+	"github.com/kyma-incubator/service-catalog-tester/internal/platform/signal"
+	"github.com/kyma-incubator/service-catalog-tester/internal/report"
+	"github.com/kyma-incubator/service-catalog-tester/internal/runner"
+	"github.com/pkg/errors"
+	"github.com/vrischmann/envconfig"
+	k8sClientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const runOnceCmd = "run-once"
+
+// OneShotConfig holds configuration for the one-shot mode.
+// In contrast to the Config, it does not require Slack and monitoring settings.
+type OneShotConfig struct {
+	Logger         logger.Config
+	KubeconfigPath string `envconfig:"optional"`
+}
+
+// runOnce executes selected tests given number of times, writes reports and returns the process exit code.
+// Non-zero exit code is returned when any test failed or when tests could not be executed.
+func runOnce(args []string) int {
+	var cfg OneShotConfig
+	err := envconfig.InitWithPrefix(&cfg, "APP")
+	fatalOnError(err, "while reading configuration from environment variables")
+
+	var testsCfg TestsConfig
+	err = envconfig.InitWithPrefix(&testsCfg, "APP")
+	fatalOnError(err, "while reading tests configuration from environment variables")
+
+	fs := flag.NewFlagSet(runOnceCmd, flag.ExitOnError)
+	selected := fs.String("tests", "", "Comma separated names of tests to execute. All tests are executed if not set.")
+	count := fs.Int("count", 1, "How many times each test is executed.")
+	junitPath := fs.String("junit-report", "", "Path to the JUnit XML report. Report is not written if not set.")
+	jsonPath := fs.String("json-report", "", "Path to the JSON report. Report is not written if not set.")
+	kubeconfig := fs.String("kubeconfig", cfg.KubeconfigPath, "Path to the kubeconfig file. In-cluster config is used if not set.")
+	fs.Parse(args)
+
+	log := logger.New(&cfg.Logger)
+	if *count < 1 {
+		log.Errorf("Invalid count %d, each test must be executed at least once", *count)
+		return 2
+	}
+	stopCh := signal.SetupChannel()
+
+	k8sConfig, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	fatalOnError(err, "while creating k8s config")
+
+	k8sCli, err := k8sClientset.NewForConfig(k8sConfig)
+	fatalOnError(err, "while creating k8s clientset")
+	scCli, err := scClientset.NewForConfig(k8sConfig)
+	fatalOnError(err, "while creating Service Catalog clientset")
+
+	diagCollector := diagnostics.NewCollector(testsCfg.Diagnostics, k8sCli, scCli, log)
+
+	toExecute, err := selectTests(newConfiguredTests(testsCfg, k8sConfig, diagCollector, metrics.NewRegistry(), log), *selected)
+	if err != nil {
+		log.Errorf("Cannot select tests: %v", err)
+		return 2
+	}
+
+	results := runner.NewOneShotRunner(log).Run(stopCh, *count, toExecute...)
+
+	if err := report.PrintSummary(os.Stdout, results); err != nil {
+		log.Errorf("Cannot print summary: %v", err)
+	}
+	if err := writeReport(*junitPath, results, report.WriteJUnit); err != nil {
+		log.Errorf("Cannot write JUnit report: %v", err)
+		return 2
+	}
+	if err := writeReport(*jsonPath, results, report.WriteJSON); err != nil {
+		log.Errorf("Cannot write JSON report: %v", err)
+		return 2
+	}
+
+	if report.HasFailures(results) {
+		return 1
+	}
+	if len(results) != len(toExecute)*(*count) {
+		log.Error("Not all requested tests were executed")
+		return 2
+	}
+	return 0
+}
+
+func selectTests(configured []runner.ConfiguredTest, selected string) ([]runner.ConfiguredTest, error) {
+	if strings.TrimSpace(selected) == "" {
+		return configured, nil
+	}
+
+	available := map[string]runner.ConfiguredTest{}
+	var names []string
+	for _, t := range configured {
+		available[t.Test.Name()] = t
+		names = append(names, t.Test.Name())
+	}
+
+	var out []runner.ConfiguredTest
+	for _, name := range strings.Split(selected, ",") {
+		name = strings.TrimSpace(name)
+		test, found := available[name]
+		if !found {
+			return nil, fmt.Errorf("test %q not found, available tests: %s", name, strings.Join(names, ", "))
+		}
+		out = append(out, test)
+	}
+
+	return out, nil
+}
+
+func writeReport(path string, results []runner.ExecutionResult, write func(w io.Writer, results []runner.ExecutionResult) error) (retErr error) {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "while creating file %q", path)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = errors.Wrapf(err, "while closing file %q", path)
+		}
+	}()
+
+	return write(f, results)
+}